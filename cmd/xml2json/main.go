@@ -1,5 +1,11 @@
 package main
 
-func main() {
+import (
+	"os"
+
+	"github.com/jdodson3106/goXml2Json/internal/cli"
+)
 
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/jdodson3106/goXml2Json/internal/token"
@@ -8,6 +9,9 @@ import (
 
 type Node interface {
 	TokenLiteral() string
+
+	// Pos is where the node starts in the parsed input
+	Pos() token.Position
 }
 
 type ElementNode interface {
@@ -17,6 +21,14 @@ type ElementNode interface {
 
 // Document the root node of all xml files to be parsed
 type Document struct {
+	// Declaration is the <?xml ...?> declaration at the start of the document, or nil if there is none
+	Declaration *Declaration
+
+	// Doctype is the <!DOCTYPE ...> of the document, or nil if there is none
+	Doctype *Doctype
+
+	// Elements holds the root elements and any comments and processing
+	// instructions around them in document order
 	Elements []ElementNode
 }
 
@@ -28,6 +40,13 @@ func (d *Document) TokenLiteral() string {
 	}
 }
 
+func (d *Document) Pos() token.Position {
+	if len(d.Elements) > 0 {
+		return d.Elements[0].Pos()
+	}
+	return token.Position{}
+}
+
 // ElementTagNode the base node of all XML elements
 type ElementTagNode struct {
 
@@ -36,18 +55,31 @@ type ElementTagNode struct {
 	// name in the literal property
 	Token token.Token
 
+	// Name is the tag name split into prefix and local name, and resolved to its namespace
+	Name Name
+
+	// Namespaces are the xmlns declarations made on the element, in the order of its attributes
+	Namespaces []Namespace
+
 	// Attributes contains all the potential key/value attributes
 	// that may be present on a given Element
 	Attributes []*ElementAttributeNode
 
 	// Elements are pointers to all the children Element tags
-	Elements []*ElementNode
+	// in the order they appear in the document
+	Elements []*ElementTagNode
 
 	// Value is the value of the element.
 	// Typically, this will be nil if the Elements property is
-	// not nil (or empty) and vice versa
+	// not nil (or empty) and vice versa. For mixed content, where text
+	// is interleaved with child elements, Value holds all the text joined together.
+	// The text of CDATA sections is part of Value as well
 	Value ElementValueNode
 
+	// Children holds the text (*ElementValueNode), element (*ElementTagNode), comment (*CommentNode),
+	// CDATA (*CDataNode) and processing instruction (*ProcInstNode) content of the element in document order
+	Children []ElementNode
+
 	// EndToken is the closing token that all xml elements need
 	// Examples are <tag></tag> or <tag/>. In the first instance the tag could have children nodes or a Value
 	// however in the second case the tag can ONLY have Attributes
@@ -56,7 +88,43 @@ type ElementTagNode struct {
 
 func (e *ElementTagNode) elementNode()         {}
 func (e *ElementTagNode) TokenLiteral() string { return e.Token.Literal }
+func (e *ElementTagNode) Pos() token.Position  { return e.Token.Pos }
+
+// HasMixedContent reports whether the element has both text and child elements
+func (e *ElementTagNode) HasMixedContent() bool {
+	return e.Value.Value != nil && len(e.Elements) > 0
+}
+
+// JoinText sets Value to the text of every text segment and CDATA section in Children, in document order.
+// Value keeps the token of the first one, and is left empty when there are none
+func (e *ElementTagNode) JoinText() {
+	e.Value = ElementValueNode{}
 
+	var builder strings.Builder
+	hasText := false
+	for _, child := range e.Children {
+		switch n := child.(type) {
+		case *ElementValueNode:
+			if !hasText {
+				e.Value.Token = n.Token
+			}
+			builder.WriteString(fmt.Sprint(n.Value))
+		case *CDataNode:
+			if !hasText {
+				e.Value.Token = n.Token
+			}
+			builder.WriteString(n.Value)
+		default:
+			continue
+		}
+		hasText = true
+	}
+	if hasText {
+		e.Value.Value = builder.String()
+	}
+}
+
+// ElementValueNode a run of text inside an element
 type ElementValueNode struct {
 	Token token.Token
 	Value interface{}
@@ -64,6 +132,63 @@ type ElementValueNode struct {
 
 func (e *ElementValueNode) elementNode()         {}
 func (e *ElementValueNode) TokenLiteral() string { return e.Token.Literal }
+func (e *ElementValueNode) Pos() token.Position  { return e.Token.Pos }
+
+// CommentNode an xml <!-- comment --> found between elements or inside element content
+type CommentNode struct {
+	Token token.Token
+
+	// Value is the text between the <!-- and --> delimiters
+	Value string
+}
+
+func (c *CommentNode) elementNode()         {}
+func (c *CommentNode) TokenLiteral() string { return c.Token.Literal }
+func (c *CommentNode) Pos() token.Position  { return c.Token.Pos }
+
+// CDataNode an xml <![CDATA[ ... ]]> section inside element content
+type CDataNode struct {
+	Token token.Token
+
+	// Value is the text between the <![CDATA[ and ]]> delimiters, exactly as written
+	Value string
+}
+
+func (c *CDataNode) elementNode()         {}
+func (c *CDataNode) TokenLiteral() string { return c.Token.Literal }
+func (c *CDataNode) Pos() token.Position  { return c.Token.Pos }
+
+// ProcInstNode an xml <?target data?> processing instruction like <?xml-stylesheet href="style.xsl"?>
+type ProcInstNode struct {
+	Token token.Token
+
+	// Target is the name following the <?
+	Target string
+
+	// Data is the rest of the instruction without the whitespace separating it from Target
+	Data string
+}
+
+func (p *ProcInstNode) elementNode()         {}
+func (p *ProcInstNode) TokenLiteral() string { return p.Token.Literal }
+func (p *ProcInstNode) Pos() token.Position  { return p.Token.Pos }
+
+// Declaration the <?xml version="1.0" encoding="UTF-8" standalone="yes"?> declaration of a document
+type Declaration struct {
+	Token token.Token
+
+	Version string
+
+	// Encoding is the declared encoding of the input, or empty when not given.
+	// The input has already been decoded using it by the time it is parsed
+	Encoding string
+
+	// Standalone is "yes", "no" or empty when not given
+	Standalone string
+}
+
+func (d *Declaration) TokenLiteral() string { return d.Token.Literal }
+func (d *Declaration) Pos() token.Position  { return d.Token.Pos }
 
 // ElementAttributeNode represents a key/value pair of attributes on an xml element
 type ElementAttributeNode struct {
@@ -76,7 +201,8 @@ type ElementAttributeNode struct {
 	Value *AttributeValueNode
 }
 
-func (e *ElementAttributeNode) elementNode() {}
+func (e *ElementAttributeNode) elementNode()        {}
+func (e *ElementAttributeNode) Pos() token.Position { return e.Key.Token.Pos }
 func (e *ElementAttributeNode) TokenLiteral() string {
 	var builder strings.Builder
 	builder.WriteString("Key=")
@@ -90,10 +216,14 @@ func (e *ElementAttributeNode) TokenLiteral() string {
 type AttributeKeyNode struct {
 	Token token.Token
 	Value string
+
+	// Name is the key split into prefix and local name. Only prefixed keys are in a namespace
+	Name Name
 }
 
 func (a *AttributeKeyNode) attributeNode()       {}
 func (a *AttributeKeyNode) TokenLiteral() string { return a.Token.Literal }
+func (a *AttributeKeyNode) Pos() token.Position  { return a.Token.Pos }
 
 // AttributeValueNode holds the Token and string value of the value on an element attribute
 type AttributeValueNode struct {
@@ -103,3 +233,4 @@ type AttributeValueNode struct {
 
 func (a *AttributeValueNode) attributeNode()       {}
 func (a *AttributeValueNode) TokenLiteral() string { return a.Token.Literal }
+func (a *AttributeValueNode) Pos() token.Position  { return a.Token.Pos }
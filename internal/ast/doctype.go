@@ -0,0 +1,122 @@
+package ast
+
+import (
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// Doctype the <!DOCTYPE ...> declaration of a document and the markup declared in its internal subset
+type Doctype struct {
+	Token token.Token
+
+	// Name is the name of the root element the document declares
+	Name string
+
+	// PublicId and SystemId identify the external DTD, and are empty when the document has none.
+	// The external DTD itself is never loaded
+	PublicId string
+	SystemId string
+
+	// Entities, Elements and Attributes hold the <!ENTITY>, <!ELEMENT> and <!ATTLIST>
+	// declarations of the internal subset in document order
+	Entities   []*EntityDecl
+	Elements   []*ElementDecl
+	Attributes []*AttributeDecl
+}
+
+func (d *Doctype) TokenLiteral() string { return d.Token.Literal }
+func (d *Doctype) Pos() token.Position  { return d.Token.Pos }
+
+// Entity returns the general entity declared as name, or nil if there is none.
+// When an entity is declared more than once the first declaration is used
+func (d *Doctype) Entity(name string) *EntityDecl {
+	for _, e := range d.Entities {
+		if !e.Parameter && e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// AttributeDefaults returns the attributes declared for element that have a default value.
+// When an attribute is declared more than once the first declaration is used
+func (d *Doctype) AttributeDefaults(element string) []*AttributeDecl {
+	var defaults []*AttributeDecl
+	seen := map[string]bool{}
+	for _, a := range d.Attributes {
+		if a.Element != element || seen[a.Name] {
+			continue
+		}
+		seen[a.Name] = true
+		if a.HasDefault() {
+			defaults = append(defaults, a)
+		}
+	}
+	return defaults
+}
+
+// EntityDecl an <!ENTITY name "value"> or <!ENTITY name SYSTEM "uri"> declaration
+type EntityDecl struct {
+	Token token.Token
+
+	Name string
+
+	// Parameter is set for <!ENTITY % name ...> entities that can only be used inside the DTD
+	Parameter bool
+
+	// Value is the replacement text of an internal entity as written, before any references in it are expanded
+	Value string
+
+	// PublicId and SystemId locate the content of an external entity
+	PublicId string
+	SystemId string
+
+	// Notation is the NDATA notation of an unparsed external entity
+	Notation string
+}
+
+func (e *EntityDecl) TokenLiteral() string { return e.Token.Literal }
+func (e *EntityDecl) Pos() token.Position  { return e.Token.Pos }
+
+// IsExternal reports whether the content of the entity lives outside of the document
+func (e *EntityDecl) IsExternal() bool {
+	return e.SystemId != ""
+}
+
+// ElementDecl an <!ELEMENT name content> declaration
+type ElementDecl struct {
+	Token token.Token
+
+	Name string
+
+	// Content is the content model as written, like EMPTY, ANY or (#PCDATA|b)*
+	Content string
+}
+
+func (e *ElementDecl) TokenLiteral() string { return e.Token.Literal }
+func (e *ElementDecl) Pos() token.Position  { return e.Token.Pos }
+
+// AttributeDecl a single attribute of an <!ATTLIST element name type default> declaration
+type AttributeDecl struct {
+	Token token.Token
+
+	// Element is the name of the element the attribute belongs to
+	Element string
+	Name    string
+
+	// Type is the attribute type as written, like CDATA, ID or (yes|no)
+	Type string
+
+	// Mode is #REQUIRED, #IMPLIED, #FIXED or empty for an attribute with a plain default value
+	Mode string
+
+	// Default is the default value of the attribute, if it has one
+	Default string
+}
+
+func (a *AttributeDecl) TokenLiteral() string { return a.Token.Literal }
+func (a *AttributeDecl) Pos() token.Position  { return a.Token.Pos }
+
+// HasDefault reports whether the attribute has a value when an element does not set it
+func (a *AttributeDecl) HasDefault() bool {
+	return a.Mode == "" || a.Mode == "#FIXED"
+}
@@ -0,0 +1,113 @@
+package ast
+
+import (
+	"slices"
+
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// JsonNode is implemented by every node that can appear in a JSON tree
+type JsonNode interface {
+	Node
+	jsonNode()
+}
+
+// JsonObjectNode an ordered collection of key/value members wrapped in curly braces
+type JsonObjectNode struct {
+	// Token is the token the object was built from. For converted
+	// documents this is the TAG token of the source element
+	Token token.Token
+
+	// Members holds the key/value pairs of the object in the order they were added
+	Members []*JsonMemberNode
+}
+
+func (j *JsonObjectNode) jsonNode()            {}
+func (j *JsonObjectNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonObjectNode) Pos() token.Position  { return j.Token.Pos }
+
+// Get returns the value stored under key or nil if the object has no such member
+func (j *JsonObjectNode) Get(key string) JsonNode {
+	for _, m := range j.Members {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+// Set replaces the value stored under key, appending a new member if the key is not present
+func (j *JsonObjectNode) Set(key string, value JsonNode) {
+	for _, m := range j.Members {
+		if m.Key == key {
+			m.Value = value
+			return
+		}
+	}
+	j.Members = append(j.Members, &JsonMemberNode{Key: key, Value: value})
+}
+
+// Delete removes the member stored under key, if any
+func (j *JsonObjectNode) Delete(key string) {
+	j.Members = slices.DeleteFunc(j.Members, func(m *JsonMemberNode) bool { return m.Key == key })
+}
+
+// JsonMemberNode a single key/value pair inside a JsonObjectNode
+type JsonMemberNode struct {
+	Token token.Token
+	Key   string
+	Value JsonNode
+}
+
+func (j *JsonMemberNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonMemberNode) Pos() token.Position  { return j.Token.Pos }
+
+// JsonArrayNode an ordered list of values wrapped in square brackets
+type JsonArrayNode struct {
+	Token    token.Token
+	Elements []JsonNode
+}
+
+func (j *JsonArrayNode) jsonNode()            {}
+func (j *JsonArrayNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonArrayNode) Pos() token.Position  { return j.Token.Pos }
+
+// JsonStringNode a JSON string value
+type JsonStringNode struct {
+	Token token.Token
+	Value string
+}
+
+func (j *JsonStringNode) jsonNode()            {}
+func (j *JsonStringNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonStringNode) Pos() token.Position  { return j.Token.Pos }
+
+// JsonNumberNode a JSON number value.
+// Value holds either an int64 or a float64
+type JsonNumberNode struct {
+	Token token.Token
+	Value interface{}
+}
+
+func (j *JsonNumberNode) jsonNode()            {}
+func (j *JsonNumberNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonNumberNode) Pos() token.Position  { return j.Token.Pos }
+
+// JsonBoolNode a JSON true or false value
+type JsonBoolNode struct {
+	Token token.Token
+	Value bool
+}
+
+func (j *JsonBoolNode) jsonNode()            {}
+func (j *JsonBoolNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonBoolNode) Pos() token.Position  { return j.Token.Pos }
+
+// JsonNullNode the JSON null value
+type JsonNullNode struct {
+	Token token.Token
+}
+
+func (j *JsonNullNode) jsonNode()            {}
+func (j *JsonNullNode) TokenLiteral() string { return j.Token.Literal }
+func (j *JsonNullNode) Pos() token.Position  { return j.Token.Pos }
@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"strings"
+)
+
+const (
+	// XmlNamespace is the namespace the xml prefix of names like xml:lang is always bound to
+	XmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+	// XmlnsNamespace is the namespace of the xmlns and xmlns:prefix attributes declaring namespaces
+	XmlnsNamespace = "http://www.w3.org/2000/xmlns/"
+)
+
+// Name the qualified name of an element or attribute like soap:Envelope, split into its prefix and local name
+type Name struct {
+	// Prefix is the part before the colon, or empty when the name has none
+	Prefix string
+	Local  string
+
+	// Space is the namespace URI the name is in, or empty when it is in no namespace
+	Space string
+}
+
+// SplitName splits a qualified name at its colon. Space is left empty
+func SplitName(qname string) Name {
+	if prefix, local, ok := strings.Cut(qname, ":"); ok {
+		return Name{Prefix: prefix, Local: local}
+	}
+	return Name{Local: qname}
+}
+
+// String returns the qualified name as written
+func (n Name) String() string {
+	if n.Prefix == "" {
+		return n.Local
+	}
+	return n.Prefix + ":" + n.Local
+}
+
+// Namespace an xmlns="uri" or xmlns:prefix="uri" declaration that binds Prefix to URI for an element
+// and its content. The default namespace has an empty Prefix, and an empty URI undeclares it
+type Namespace struct {
+	Prefix string
+	URI    string
+}
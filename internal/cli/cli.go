@@ -0,0 +1,244 @@
+// Package cli implements the xml2json command
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/converter"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	"github.com/jdodson3106/goXml2Json/internal/parser"
+)
+
+const usage = `Usage: xml2json [flags] [file]
+
+Converts the xml in file (or stdin when no file is given) to JSON.
+With --reverse the input is read as JSON and converted to xml.
+
+Flags:
+`
+
+// Run executes the command with args, not including the program name, and returns the process exit code
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("xml2json", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	output := flags.String("o", "", "write the JSON to `file` instead of stdout")
+	indent := flags.Int("indent", 2, "number of spaces used to indent nested values")
+	compact := flags.Bool("compact", false, "write the JSON on a single line")
+	convention := flags.String("convention", "default", "`name` of the mapping rules: default, badgerfish, parker, gdata or jsonml")
+	mixed := flags.String("mixed", "text", "render mixed content as joined `text` or ordered segments")
+	attrPrefix := flags.String("attribute-prefix", converter.AttributePrefix, "`prefix` of attribute keys like @, - or _, or none when empty")
+	textKey := flags.String("text-key", converter.TextKey, "`key` of the text of elements with attributes or children like #text, _ or value")
+	whitespace := flags.String("whitespace", "trim", "`mode` for the whitespace in element text: trim, collapse or preserve (the default for jsonml)")
+	inferTypes := flags.Bool("infer-types", false, "write numeric and true/false values as JSON numbers and booleans")
+	stringKeys := flags.String("string-keys", "", "comma separated element and attribute `names` kept as strings by --infer-types")
+	comments := flags.Bool("comments", false, "keep xml comments under \"#comment\" keys instead of dropping them")
+	cdata := flags.Bool("cdata", false, "write the text of CDATA sections under \"#cdata\" keys instead of as plain text")
+	procInsts := flags.Bool("pi", false, "keep processing instructions under \"?target\" keys instead of dropping them")
+	partial := flags.Bool("partial", false, "still write the conversion of whatever could be recovered from malformed xml")
+	reverse := flags.Bool("reverse", false, "convert JSON input to xml")
+	root := flags.String("root", converter.DefaultRootName, "`name` of the root element created by --reverse when the JSON has no single root member")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "xml2json: too many arguments, expected at most one input file")
+		return 2
+	}
+	if *indent < 0 {
+		fmt.Fprintln(stderr, "xml2json: -indent must not be negative")
+		return 2
+	}
+
+	conventionRules, err := parseConvention(*convention)
+	if err != nil {
+		fmt.Fprintf(stderr, "xml2json: %v\n", err)
+		return 2
+	}
+	if *textKey == "" {
+		fmt.Fprintln(stderr, "xml2json: -text-key must not be empty")
+		return 2
+	}
+	mixedMode, err := parseMixedMode(*mixed)
+	if err != nil {
+		fmt.Fprintf(stderr, "xml2json: %v\n", err)
+		return 2
+	}
+	whitespaceMode, ok := parser.ParseWhitespaceMode(*whitespace)
+	if !ok {
+		fmt.Fprintf(stderr, "xml2json: unknown -whitespace mode %q, expected trim, collapse or preserve\n", *whitespace)
+		return 2
+	}
+	if conventionRules == converter.ConventionJsonML && !isFlagSet(flags, "whitespace") {
+		// JsonML is lossless, so the text is kept exactly as written unless asked otherwise
+		whitespaceMode = parser.WhitespacePreserve
+	}
+
+	input, name, err := openInput(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "xml2json: %v\n", err)
+		return 1
+	}
+	defer input.Close()
+
+	opts := converter.Options{
+		Indent:          strings.Repeat(" ", *indent),
+		Convention:      conventionRules,
+		AttributePrefix: attrPrefix,
+		TextKey:         *textKey,
+		MixedContent:    mixedMode,
+		InferTypes:      *inferTypes,
+		RootName:        *root,
+		KeepComments:    *comments,
+		MarkCData:       *cdata,
+		KeepProcInsts:   *procInsts,
+	}
+	if *stringKeys != "" {
+		opts.StringKeys = strings.Split(*stringKeys, ",")
+	}
+	if *compact {
+		opts.Indent = ""
+	}
+	c := converter.New(opts)
+
+	format := "xml"
+	convert := func(input io.Reader, c *converter.Converter) ([]byte, []*parser.ParseError, error) {
+		return xmlToJson(input, c, parser.Options{Whitespace: whitespaceMode})
+	}
+	if *reverse {
+		format, convert = "JSON", jsonToXml
+	}
+
+	status := 0
+	out, parseErrs, err := convert(input, c)
+	if len(parseErrs) > 0 {
+		fmt.Fprintf(stderr, "xml2json: %s is not valid %s:\n", name, format)
+		for _, e := range parseErrs {
+			fmt.Fprintf(stderr, "  %s\n", e)
+		}
+		if !*partial || out == nil {
+			return 1
+		}
+		status = 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "xml2json: %v\n", err)
+		return 1
+	}
+	out = append(out, '\n')
+
+	if *output == "" {
+		_, err = stdout.Write(out)
+	} else {
+		err = os.WriteFile(*output, out, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "xml2json: %v\n", err)
+		return 1
+	}
+	return status
+}
+
+// xmlToJson decodes input using its declared encoding, parses it as xml and converts it to JSON.
+// The lexer reads input incrementally, but the whole document is parsed before it is converted.
+// Any errors collected by the parser are returned as parseErrs along with
+// the conversion of the document the parser recovered
+func xmlToJson(input io.Reader, c *converter.Converter, opts parser.Options) (out []byte, parseErrs []*parser.ParseError, err error) {
+	text, err := lexer.NewDecoder(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l, err := lexer.NewReader(text, lexer.XML)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := parser.NewWithOptions(l, opts)
+	doc := p.ParseDocument()
+	if err := l.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	out, err = c.ToJson(doc)
+	return out, p.Errors(), err
+}
+
+// jsonToXml parses input as JSON and converts it to xml.
+// Any errors collected by the parser are returned as parseErrs
+func jsonToXml(input io.Reader, c *converter.Converter) (out []byte, parseErrs []*parser.ParseError, err error) {
+	l, err := lexer.NewReader(input, lexer.JSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := parser.NewJson(l)
+	node := p.ParseJson()
+	if err := l.Err(); err != nil {
+		return nil, nil, err
+	}
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	out, err = c.ToXml(node)
+	return out, nil, err
+}
+
+// openInput opens the named file, or stdin when the name is empty or "-"
+func openInput(file string, stdin io.Reader) (io.ReadCloser, string, error) {
+	if file == "" || file == "-" {
+		return io.NopCloser(stdin), "stdin", nil
+	}
+
+	f, err := os.Open(file)
+	return f, file, err
+}
+
+// isFlagSet reports whether the flag called name was given on the command line
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func parseMixedMode(mode string) (converter.MixedContentMode, error) {
+	switch mode {
+	case "text":
+		return converter.MixedText, nil
+	case "segments":
+		return converter.MixedSegments, nil
+	default:
+		return 0, fmt.Errorf("unknown -mixed mode %q, expected text or segments", mode)
+	}
+}
+
+func parseConvention(name string) (converter.Convention, error) {
+	switch name {
+	case "default":
+		return converter.ConventionDefault, nil
+	case "badgerfish":
+		return converter.ConventionBadgerFish, nil
+	case "parker":
+		return converter.ConventionParker, nil
+	case "gdata":
+		return converter.ConventionGData, nil
+	case "jsonml":
+		return converter.ConventionJsonML, nil
+	default:
+		return 0, fmt.Errorf("unknown -convention %q, expected default, badgerfish, parker, gdata or jsonml", name)
+	}
+}
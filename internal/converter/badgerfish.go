@@ -0,0 +1,259 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+)
+
+const (
+	// BadgerFishTextKey holds the text of an element when using ConventionBadgerFish
+	BadgerFishTextKey = "$"
+
+	// BadgerFishNamespaceKey holds the namespaces in scope for an element when using ConventionBadgerFish.
+	// The default namespace is keyed by BadgerFishTextKey and every other one by its prefix
+	BadgerFishNamespaceKey = AttributePrefix + "xmlns"
+)
+
+/*
+convertBadgerFish builds the BadgerFish object for el, where scope holds the namespaces
+declared by the ancestors of el. The rules are:
+  - every element becomes an object, even when it is empty
+  - attributes are keyed by AttributePrefix + name, apart from the xmlns declarations
+  - the text is keyed by BadgerFishTextKey. Mixed content is always joined
+  - the namespaces in scope are keyed by BadgerFishNamespaceKey, on every element they apply to
+  - child elements, comments, CDATA sections and processing instructions are keyed like the default rules
+*/
+func (c *Converter) convertBadgerFish(el *ast.ElementTagNode, scope []ast.Namespace) (ast.JsonNode, error) {
+	scope = append(scope[:len(scope):len(scope)], el.Namespaces...)
+
+	obj := &ast.JsonObjectNode{Token: el.Token}
+	for _, attr := range el.Attributes {
+		if attr == nil || isXmlnsName(attr.Key.Name) {
+			continue
+		}
+		obj.Members = append(obj.Members, &ast.JsonMemberNode{
+			Token: attr.Key.Token,
+			Key:   AttributePrefix + attr.Key.Value,
+			Value: c.valueNode(attr.Key.Value, attr.Value.Token, attr.Value.Value),
+		})
+	}
+
+	if len(scope) > 0 {
+		obj.Members = append(obj.Members, &ast.JsonMemberNode{Token: el.Token, Key: BadgerFishNamespaceKey, Value: badgerFishNamespaces(el, scope)})
+	}
+
+	if text, hasText := c.elementText(el); hasText {
+		obj.Members = append(obj.Members, &ast.JsonMemberNode{
+			Token: el.Value.Token,
+			Key:   BadgerFishTextKey,
+			Value: c.valueNode(el.Token.Literal, el.Value.Token, text),
+		})
+	}
+
+	g := newGrouper(obj)
+	for _, cdata := range c.cdataSections(el) {
+		g.add(cdata.Token, CDataKey, &ast.JsonStringNode{Token: cdata.Token, Value: cdata.Value})
+	}
+	for _, m := range c.markup(el) {
+		g.add(m.Token, m.Key, m.Value)
+	}
+	for _, child := range el.Elements {
+		if child == nil {
+			continue
+		}
+		node, err := c.convertBadgerFish(child, scope)
+		if err != nil {
+			return nil, err
+		}
+		g.add(child.Token, child.Token.Literal, node)
+	}
+
+	return obj, nil
+}
+
+// badgerFishNamespaces builds the object of the namespaces in scope for el in the order they were first declared.
+// A later declaration of the same prefix replaces the URI, and undeclaring the default namespace removes it
+func badgerFishNamespaces(el *ast.ElementTagNode, scope []ast.Namespace) *ast.JsonObjectNode {
+	obj := &ast.JsonObjectNode{Token: el.Token}
+	for _, ns := range scope {
+		key := ns.Prefix
+		if key == "" {
+			key = BadgerFishTextKey
+		}
+		if ns.URI == "" {
+			obj.Delete(key)
+			continue
+		}
+		obj.Set(key, &ast.JsonStringNode{Token: el.Token, Value: ns.URI})
+	}
+	return obj
+}
+
+// reverseBadgerFish builds the xml document for a BadgerFish object. An object with a single member that is
+// an object, a scalar or null is used as the document root, anything else becomes an element named Options.RootName
+func (c *Converter) reverseBadgerFish(node ast.JsonNode) (*ast.Document, error) {
+	obj, ok := node.(*ast.JsonObjectNode)
+	if !ok {
+		return nil, fmt.Errorf("a BadgerFish document must be an object, got %T", node)
+	}
+
+	if !hasSingleRoot(obj, isBadgerFishKey) {
+		rootName := c.opts.RootName
+		if rootName == "" {
+			rootName = DefaultRootName
+		}
+		root, err := c.reverseBadgerFishElement(rootName, obj, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Document{Elements: []ast.ElementNode{root}}, nil
+	}
+
+	doc := &ast.Document{}
+	for _, m := range obj.Members {
+		if isMarkupKey(m.Key) {
+			nodes, err := markupNodes(m.Key, m.Value)
+			if err != nil {
+				return nil, err
+			}
+			doc.Elements = append(doc.Elements, nodes...)
+			continue
+		}
+		el, err := c.reverseBadgerFishElement(m.Key, m.Value, nil)
+		if err != nil {
+			return nil, err
+		}
+		doc.Elements = append(doc.Elements, el)
+	}
+	return doc, nil
+}
+
+// reverseBadgerFishElement builds the element called name holding value, where scope holds the namespaces
+// declared by its ancestors. Only the namespaces under BadgerFishNamespaceKey that are not already in scope
+// are declared, and an element without BadgerFishNamespaceKey inherits the namespaces of its parent
+func (c *Converter) reverseBadgerFishElement(name string, value ast.JsonNode, scope []ast.Namespace) (*ast.ElementTagNode, error) {
+	if !isQName(name) {
+		return nil, fmt.Errorf("key %q is not a valid xml element name", name)
+	}
+	el := newElement(name)
+
+	switch v := value.(type) {
+	case *ast.JsonObjectNode:
+		if namespaces := v.Get(BadgerFishNamespaceKey); namespaces != nil {
+			if err := declareNamespaces(el, namespaces, scope); err != nil {
+				return nil, err
+			}
+		}
+		scope = append(scope[:len(scope):len(scope)], el.Namespaces...)
+
+		for _, m := range v.Members {
+			if err := c.reverseBadgerFishMember(el, m, scope); err != nil {
+				return nil, err
+			}
+		}
+	case *ast.JsonArrayNode:
+		return nil, fmt.Errorf("array in array for element %s can not be written as BadgerFish xml", name)
+	default:
+		text, ok := scalarText(value)
+		if !ok {
+			return nil, fmt.Errorf("unexpected JSON node %T for element %s", value, name)
+		}
+		if _, isNull := value.(*ast.JsonNullNode); !isNull {
+			appendText(el, text)
+		}
+	}
+
+	closeElement(el)
+	return el, nil
+}
+
+func (c *Converter) reverseBadgerFishMember(el *ast.ElementTagNode, m *ast.JsonMemberNode, scope []ast.Namespace) error {
+	switch {
+	case m.Key == BadgerFishNamespaceKey:
+		// declared before any other attribute
+	case m.Key == BadgerFishTextKey:
+		text, ok := scalarText(m.Value)
+		if !ok {
+			return fmt.Errorf("%s of element %s must be a string, number, boolean or null", BadgerFishTextKey, el.Token.Literal)
+		}
+		appendText(el, text)
+	case strings.HasPrefix(m.Key, AttributePrefix):
+		return reverseAttribute(el, m, strings.TrimPrefix(m.Key, AttributePrefix))
+	case isMarkupKey(m.Key), m.Key == CDataKey:
+		return c.reverseMember(el, m)
+	default:
+		values := []ast.JsonNode{m.Value}
+		if arr, ok := m.Value.(*ast.JsonArrayNode); ok {
+			values = arr.Elements
+		}
+		for _, v := range values {
+			child, err := c.reverseBadgerFishElement(m.Key, v, scope)
+			if err != nil {
+				return err
+			}
+			appendElement(el, child)
+		}
+	}
+	return nil
+}
+
+// declareNamespaces adds an xmlns attribute to el for every namespace in the BadgerFishNamespaceKey object
+// that is not already in scope, and undeclares the default namespace when the object leaves it out
+func declareNamespaces(el *ast.ElementTagNode, value ast.JsonNode, scope []ast.Namespace) error {
+	obj, ok := value.(*ast.JsonObjectNode)
+	if !ok {
+		return fmt.Errorf("%s of element %s must be an object", BadgerFishNamespaceKey, el.Token.Literal)
+	}
+
+	declare := func(ns ast.Namespace) {
+		attr := "xmlns"
+		if ns.Prefix != "" {
+			attr += ":" + ns.Prefix
+		}
+		el.Attributes = append(el.Attributes, newAttribute(attr, ns.URI))
+		el.Namespaces = append(el.Namespaces, ns)
+	}
+
+	for _, m := range obj.Members {
+		uri, ok := m.Value.(*ast.JsonStringNode)
+		if !ok || uri.Value == "" {
+			return fmt.Errorf("namespace %q of element %s must be a non empty string", m.Key, el.Token.Literal)
+		}
+		ns := ast.Namespace{Prefix: m.Key, URI: uri.Value}
+		if m.Key == BadgerFishTextKey {
+			ns.Prefix = ""
+		} else if !lexer.IsName(m.Key) || strings.Contains(m.Key, ":") {
+			return fmt.Errorf("key %q is not a valid namespace prefix", m.Key)
+		}
+		if lookupNamespace(scope, ns.Prefix) != ns.URI {
+			declare(ns)
+		}
+	}
+
+	if obj.Get(BadgerFishTextKey) == nil && lookupNamespace(scope, "") != "" {
+		declare(ast.Namespace{})
+	}
+	return nil
+}
+
+// lookupNamespace returns the URI prefix is bound to in scope, or an empty string when it is not bound
+func lookupNamespace(scope []ast.Namespace, prefix string) string {
+	for i := len(scope) - 1; i >= 0; i-- {
+		if scope[i].Prefix == prefix {
+			return scope[i].URI
+		}
+	}
+	return ""
+}
+
+// isXmlnsName reports whether name is an xmlns or xmlns:prefix namespace declaration
+func isXmlnsName(name ast.Name) bool {
+	return name.Prefix == "xmlns" || name.Prefix == "" && name.Local == "xmlns"
+}
+
+func isBadgerFishKey(key string) bool {
+	return key == BadgerFishTextKey || strings.HasPrefix(key, AttributePrefix) || key == CDataKey || isMarkupKey(key)
+}
@@ -0,0 +1,447 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+const (
+	// AttributePrefix is prepended to every attribute name so attributes
+	// can never collide with child elements of the same name. Options.AttributePrefix replaces it
+	AttributePrefix = "@"
+
+	// TextKey holds the text of an element that also has attributes or children. Options.TextKey replaces it
+	TextKey = "#text"
+
+	// ContentKey holds the ordered segments of a mixed content element when using MixedSegments
+	ContentKey = "#content"
+
+	// CommentKey holds the text of the comments inside an element when Options.KeepComments is set
+	CommentKey = "#comment"
+
+	// CDataKey holds the text of the CDATA sections inside an element when Options.MarkCData is set
+	CDataKey = "#cdata"
+
+	// ProcInstPrefix is prepended to the target of a processing instruction to key its data
+	// when Options.KeepProcInsts is set, so <?xml-stylesheet href="a.xsl"?> becomes "?xml-stylesheet"
+	ProcInstPrefix = "?"
+)
+
+// MixedContentMode selects how elements with text interleaved with child elements are rendered
+type MixedContentMode int
+
+const (
+	// MixedText joins every text segment under TextKey and groups the child
+	// elements by tag name like any other element. The relative order of
+	// text and elements is lost
+	MixedText MixedContentMode = iota
+
+	// MixedSegments keeps the content in document order as an array under ContentKey.
+	// Text segments are strings and every child element is an object holding
+	// a single member keyed by its tag name
+	MixedSegments
+)
+
+// Convention selects the rules used to map xml to JSON and back
+type Convention int
+
+const (
+	// ConventionDefault uses the rules described on Converter and Reverse
+	ConventionDefault Convention = iota
+
+	// ConventionBadgerFish keys attributes by AttributePrefix + name, text by BadgerFishTextKey
+	// and the namespaces in scope by BadgerFishNamespaceKey, see convertBadgerFish
+	ConventionBadgerFish
+
+	// ConventionParker drops attributes and keeps only the element structure and text, see convertParker
+	ConventionParker
+
+	// ConventionGData keys attributes by their plain name and text by GDataTextKey like Google Data feeds, see convertGData
+	ConventionGData
+
+	// ConventionJsonML writes every element as an array of its tag name, attributes and content in document order,
+	// see convertJsonML
+	ConventionJsonML
+)
+
+// Options controls how a Converter renders its output in either direction
+type Options struct {
+	// Indent is written once per nesting level when producing JSON bytes.
+	// An empty Indent produces compact output
+	Indent string
+
+	// Convention selects the mapping rules. The zero value is ConventionDefault
+	Convention Convention
+
+	// AttributePrefix replaces the AttributePrefix constant in the default rules, like "-" or "_".
+	// The constant is used when nil. An empty prefix keys attributes by their plain name: an attribute
+	// and a child element with the same name then share a key and are collected into an array, and
+	// Reverse reads every member that is not a reserved key back as an element
+	AttributePrefix *string
+
+	// TextKey replaces the TextKey constant in the default rules, like "_" or "value".
+	// The constant is used when empty. Converting an element or attribute that would be keyed
+	// like the text, or an element whose name starts with the attribute prefix, is an error
+	TextKey string
+
+	// MixedContent selects how mixed content elements are rendered.
+	// The zero value is MixedText
+	MixedContent MixedContentMode
+
+	// InferTypes renders element text and attribute values that are INT, FLOAT or BOOL
+	// literals (see token.LookupValue) as JSON numbers and booleans instead of strings
+	InferTypes bool
+
+	// StringKeys lists element and attribute names whose values are always kept as strings
+	// when InferTypes is set, for identifiers that happen to look like numbers such as phone numbers
+	StringKeys []string
+
+	// RootName names the root element created by Reverse when the JSON value does not
+	// have a single member to use as the root. DefaultRootName is used when empty
+	RootName string
+
+	// KeepComments renders xml comments under CommentKey instead of dropping them
+	KeepComments bool
+
+	// MarkCData renders the text of CDATA sections under CDataKey so the JSON records where it came from.
+	// By default CDATA is plain text
+	MarkCData bool
+
+	// KeepProcInsts renders processing instructions under ProcInstPrefix + target instead of dropping them.
+	// The xml declaration is not a processing instruction and is never rendered
+	KeepProcInsts bool
+}
+
+// DefaultOptions returns the options used by the xml2json command when no flags are given
+func DefaultOptions() Options {
+	return Options{Indent: "  ", RootName: DefaultRootName}
+}
+
+/*
+Converter walks an ast.Document and builds the equivalent JSON tree.
+
+The default mapping rules are:
+  - the document becomes an object keyed by the names of its root elements
+  - an element with no attributes and no children becomes its text as a string,
+    or null when it has no text either
+  - any other element becomes an object where attributes are keyed by
+    AttributePrefix + name, the text is keyed by TextKey, and every child
+    element is keyed by its tag name. Options.AttributePrefix and Options.TextKey
+    rename the first two
+  - sibling elements sharing a tag name are collected, in document order, into an
+    array stored at the position of the first occurrence
+  - mixed content is rendered according to Options.MixedContent
+  - comments are dropped unless Options.KeepComments is set, in which case
+    they are keyed by CommentKey
+  - CDATA sections are text unless Options.MarkCData is set, in which case
+    they are keyed by CDataKey
+  - processing instructions are dropped unless Options.KeepProcInsts is set,
+    in which case their data is keyed by ProcInstPrefix + target
+  - text and attribute values are strings unless Options.InferTypes is set
+*/
+type Converter struct {
+	opts Options
+
+	attrPrefix string
+	textKey    string
+}
+
+func New(opts Options) *Converter {
+	c := &Converter{opts: opts, attrPrefix: AttributePrefix, textKey: opts.TextKey}
+	if opts.AttributePrefix != nil {
+		c.attrPrefix = *opts.AttributePrefix
+	}
+	if c.textKey == "" {
+		c.textKey = TextKey
+	}
+	return c
+}
+
+// Convert builds the JSON tree for doc
+func (c *Converter) Convert(doc *ast.Document) (ast.JsonNode, error) {
+	if doc == nil {
+		return nil, errors.New("cannot convert a nil document")
+	}
+
+	switch c.opts.Convention {
+	case ConventionBadgerFish:
+		return c.convertRoots(doc, func(el *ast.ElementTagNode) (ast.JsonNode, error) {
+			return c.convertBadgerFish(el, nil)
+		})
+	case ConventionParker:
+		return c.convertRoots(doc, c.convertParker)
+	case ConventionGData:
+		return c.convertRoots(doc, c.convertGData)
+	case ConventionJsonML:
+		return convertJsonMLDocument(doc)
+	default:
+		return c.convertRoots(doc, c.convertElement)
+	}
+}
+
+// convertRoots builds the object keyed by the root elements of doc, converting each one with convert
+func (c *Converter) convertRoots(doc *ast.Document, convert func(*ast.ElementTagNode) (ast.JsonNode, error)) (ast.JsonNode, error) {
+	root := &ast.JsonObjectNode{}
+	g := newGrouper(root)
+	for _, el := range doc.Elements {
+		switch n := el.(type) {
+		case *ast.ElementTagNode:
+			node, err := convert(n)
+			if err != nil {
+				return nil, err
+			}
+			g.add(n.Token, c.elementKey(n), node)
+		case *ast.CommentNode, *ast.ProcInstNode:
+			if m := c.markupMember(n); m != nil {
+				g.add(m.Token, m.Key, m.Value)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected root node %T", el)
+		}
+	}
+
+	return root, nil
+}
+
+// ToJson converts doc and encodes the result using the configured indent
+func (c *Converter) ToJson(doc *ast.Document) ([]byte, error) {
+	node, err := c.Convert(doc)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(node, c.opts.Indent), nil
+}
+
+func (c *Converter) convertElement(el *ast.ElementTagNode) (ast.JsonNode, error) {
+	if c.isReservedKey(el.Token.Literal) {
+		return nil, fmt.Errorf("element %s can not be keyed by its name as it collides with the text key or attribute prefix", el.Token.Literal)
+	}
+	for _, attr := range el.Attributes {
+		if attr != nil && c.isSpecialKey(c.attrPrefix+attr.Key.Value) {
+			return nil, fmt.Errorf("attribute %s of element %s can not be keyed %q as it collides with the text key",
+				attr.Key.Value, el.Token.Literal, c.attrPrefix+attr.Key.Value)
+		}
+	}
+
+	text, hasText := c.elementText(el)
+	markup := c.markup(el)
+	sections := c.cdataSections(el)
+
+	if len(el.Attributes) == 0 && len(el.Elements) == 0 && len(markup) == 0 && len(sections) == 0 {
+		if !hasText {
+			return &ast.JsonNullNode{Token: el.Token}, nil
+		}
+		return c.valueNode(el.Token.Literal, el.Value.Token, text), nil
+	}
+
+	// attributes go through the grouper too as they share keys with child elements when there is no prefix
+	obj := &ast.JsonObjectNode{Token: el.Token}
+	g := newGrouper(obj)
+	for _, attr := range el.Attributes {
+		if attr == nil {
+			continue
+		}
+		g.add(attr.Key.Token, c.attrPrefix+attr.Key.Value, c.valueNode(attr.Key.Value, attr.Value.Token, attr.Value.Value))
+	}
+
+	if el.HasMixedContent() && c.opts.MixedContent == MixedSegments {
+		content, err := c.convertSegments(el)
+		if err != nil {
+			return nil, err
+		}
+		g.add(el.Token, ContentKey, content)
+		return obj, nil
+	}
+
+	if hasText {
+		g.add(el.Value.Token, c.textKey, c.valueNode(el.Token.Literal, el.Value.Token, text))
+	}
+
+	for _, cdata := range sections {
+		g.add(cdata.Token, CDataKey, &ast.JsonStringNode{Token: cdata.Token, Value: cdata.Value})
+	}
+	for _, m := range markup {
+		g.add(m.Token, m.Key, m.Value)
+	}
+	for _, child := range el.Elements {
+		if child == nil {
+			continue
+		}
+		node, err := c.convertElement(child)
+		if err != nil {
+			return nil, err
+		}
+		g.add(child.Token, child.Token.Literal, node)
+	}
+
+	return obj, nil
+}
+
+// convertSegments renders the children of el in document order
+func (c *Converter) convertSegments(el *ast.ElementTagNode) (ast.JsonNode, error) {
+	arr := &ast.JsonArrayNode{Token: el.Token}
+	for _, child := range el.Children {
+		switch n := child.(type) {
+		case *ast.ElementValueNode:
+			arr.Elements = append(arr.Elements, &ast.JsonStringNode{Token: n.Token, Value: fmt.Sprint(n.Value)})
+		case *ast.ElementTagNode:
+			node, err := c.convertElement(n)
+			if err != nil {
+				return nil, err
+			}
+			segment := &ast.JsonObjectNode{Token: n.Token}
+			segment.Set(n.Token.Literal, node)
+			arr.Elements = append(arr.Elements, segment)
+		case *ast.CDataNode:
+			if !c.opts.MarkCData {
+				arr.Elements = append(arr.Elements, &ast.JsonStringNode{Token: n.Token, Value: n.Value})
+				continue
+			}
+			segment := &ast.JsonObjectNode{Token: n.Token}
+			segment.Set(CDataKey, &ast.JsonStringNode{Token: n.Token, Value: n.Value})
+			arr.Elements = append(arr.Elements, segment)
+		case *ast.CommentNode, *ast.ProcInstNode:
+			if m := c.markupMember(n); m != nil {
+				arr.Elements = append(arr.Elements, &ast.JsonObjectNode{Token: m.Token, Members: []*ast.JsonMemberNode{m}})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected child node %T in element %s", child, el.Token.Literal)
+		}
+	}
+	return arr, nil
+}
+
+// elementKey returns the key el is stored under in the object of its parent
+func (c *Converter) elementKey(el *ast.ElementTagNode) string {
+	if c.opts.Convention == ConventionGData {
+		return gdataName(el.Token.Literal)
+	}
+	return el.Token.Literal
+}
+
+// valueNode builds the JSON value for the text of the element or attribute called name.
+// Without InferTypes, or for names listed in StringKeys, this is always a string
+func (c *Converter) valueNode(name string, tok token.Token, text string) ast.JsonNode {
+	if !c.opts.InferTypes || slices.Contains(c.opts.StringKeys, name) {
+		return &ast.JsonStringNode{Token: tok, Value: text}
+	}
+
+	switch token.LookupValue(text) {
+	case token.INT:
+		v, _ := strconv.ParseInt(text, 10, 64)
+		return &ast.JsonNumberNode{Token: token.Token{Type: token.INT, Literal: text, Pos: tok.Pos}, Value: v}
+	case token.FLOAT:
+		v, _ := strconv.ParseFloat(text, 64)
+		return &ast.JsonNumberNode{Token: token.Token{Type: token.FLOAT, Literal: text, Pos: tok.Pos}, Value: v}
+	case token.BOOL:
+		return &ast.JsonBoolNode{Token: token.Token{Type: token.BOOL, Literal: text, Pos: tok.Pos}, Value: text == "true"}
+	default:
+		return &ast.JsonStringNode{Token: tok, Value: text}
+	}
+}
+
+// markup returns the members for the comments and processing instructions of el that are rendered
+func (c *Converter) markup(el *ast.ElementTagNode) []*ast.JsonMemberNode {
+	var members []*ast.JsonMemberNode
+	for _, child := range el.Children {
+		if m := c.markupMember(child); m != nil {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// markupMember returns the member a comment or processing instruction is rendered as,
+// or nil when node is neither or the options drop it
+func (c *Converter) markupMember(node ast.ElementNode) *ast.JsonMemberNode {
+	if c.opts.Convention == ConventionParker {
+		return nil
+	}
+	switch n := node.(type) {
+	case *ast.CommentNode:
+		if c.opts.KeepComments {
+			return &ast.JsonMemberNode{Token: n.Token, Key: CommentKey, Value: &ast.JsonStringNode{Token: n.Token, Value: n.Value}}
+		}
+	case *ast.ProcInstNode:
+		if c.opts.KeepProcInsts {
+			return &ast.JsonMemberNode{Token: n.Token, Key: ProcInstPrefix + n.Target, Value: &ast.JsonStringNode{Token: n.Token, Value: n.Data}}
+		}
+	}
+	return nil
+}
+
+// cdataSections returns the CDATA sections of el that are rendered under CDataKey,
+// which is none unless MarkCData is set
+func (c *Converter) cdataSections(el *ast.ElementTagNode) []*ast.CDataNode {
+	if !c.opts.MarkCData {
+		return nil
+	}
+	var sections []*ast.CDataNode
+	for _, child := range el.Children {
+		if cdata, ok := child.(*ast.CDataNode); ok {
+			sections = append(sections, cdata)
+		}
+	}
+	return sections
+}
+
+// elementText returns the text value of el and whether it has one at all.
+// The text of CDATA sections is left out when they are rendered under CDataKey
+func (c *Converter) elementText(el *ast.ElementTagNode) (string, bool) {
+	if el.Value.Value == nil {
+		return "", false
+	}
+	if len(c.cdataSections(el)) == 0 {
+		return fmt.Sprint(el.Value.Value), true
+	}
+
+	var builder strings.Builder
+	hasText := false
+	for _, child := range el.Children {
+		if text, ok := child.(*ast.ElementValueNode); ok {
+			builder.WriteString(fmt.Sprint(text.Value))
+			hasText = true
+		}
+	}
+	return builder.String(), hasText
+}
+
+// grouper adds members to an object, folding repeated keys into an array
+// that stays at the position of the first occurrence
+type grouper struct {
+	obj    *ast.JsonObjectNode
+	seen   map[string]*ast.JsonMemberNode
+	arrays map[string]*ast.JsonArrayNode
+}
+
+func newGrouper(obj *ast.JsonObjectNode) *grouper {
+	return &grouper{
+		obj:    obj,
+		seen:   map[string]*ast.JsonMemberNode{},
+		arrays: map[string]*ast.JsonArrayNode{},
+	}
+}
+
+func (g *grouper) add(tok token.Token, key string, value ast.JsonNode) {
+	member, ok := g.seen[key]
+	if !ok {
+		member = &ast.JsonMemberNode{Token: tok, Key: key, Value: value}
+		g.seen[key] = member
+		g.obj.Members = append(g.obj.Members, member)
+		return
+	}
+
+	arr, ok := g.arrays[key]
+	if !ok {
+		arr = &ast.JsonArrayNode{Token: member.Token, Elements: []ast.JsonNode{member.Value}}
+		g.arrays[key] = arr
+		member.Value = arr
+	}
+	arr.Elements = append(arr.Elements, value)
+}
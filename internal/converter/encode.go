@@ -0,0 +1,153 @@
+package converter
+
+import (
+	"bytes"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// Encode writes node out as JSON text.
+// When indent is empty the output is compact, otherwise every nested
+// value is placed on its own line and prefixed with indent once per level
+func Encode(node ast.JsonNode, indent string) []byte {
+	var buf bytes.Buffer
+	e := &encoder{buf: &buf, indent: indent}
+	e.writeNode(node, 0)
+	return buf.Bytes()
+}
+
+type encoder struct {
+	buf    *bytes.Buffer
+	indent string
+}
+
+func (e *encoder) writeNode(node ast.JsonNode, depth int) {
+	switch n := node.(type) {
+	case *ast.JsonObjectNode:
+		e.writeObject(n, depth)
+	case *ast.JsonArrayNode:
+		e.writeArray(n, depth)
+	case *ast.JsonStringNode:
+		e.writeString(n.Value)
+	case *ast.JsonNumberNode:
+		e.writeNumber(n)
+	case *ast.JsonBoolNode:
+		e.buf.WriteString(strconv.FormatBool(n.Value))
+	default:
+		// nil nodes and *ast.JsonNullNode are both written as null
+		e.buf.WriteString("null")
+	}
+}
+
+func (e *encoder) writeObject(obj *ast.JsonObjectNode, depth int) {
+	if len(obj.Members) == 0 {
+		e.buf.WriteString("{}")
+		return
+	}
+
+	e.buf.WriteByte('{')
+	for i, m := range obj.Members {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		e.writeString(m.Key)
+		e.buf.WriteByte(':')
+		if e.indent != "" {
+			e.buf.WriteByte(' ')
+		}
+		e.writeNode(m.Value, depth+1)
+	}
+	e.newline(depth)
+	e.buf.WriteByte('}')
+}
+
+func (e *encoder) writeArray(arr *ast.JsonArrayNode, depth int) {
+	if len(arr.Elements) == 0 {
+		e.buf.WriteString("[]")
+		return
+	}
+
+	e.buf.WriteByte('[')
+	for i, el := range arr.Elements {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		e.writeNode(el, depth+1)
+	}
+	e.newline(depth)
+	e.buf.WriteByte(']')
+}
+
+// writeNumber writes the literal of n, which the JSON parser and token.LookupValue have already validated,
+// so large and precise numbers are written exactly as they were read
+func (e *encoder) writeNumber(n *ast.JsonNumberNode) {
+	if n.Token.Literal != "" {
+		e.buf.WriteString(n.Token.Literal)
+		return
+	}
+
+	switch v := n.Value.(type) {
+	case int64:
+		e.buf.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		e.buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		e.buf.WriteString(n.Token.Literal)
+	}
+}
+
+// writeString quotes s following the JSON string grammar.
+// Unlike encoding/json, markup characters such as '<' and '&' are left as is
+// since they are common in converted xml text
+func (e *encoder) writeString(s string) {
+	e.buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				e.buf.WriteByte('\\')
+				e.buf.WriteByte(c)
+			case c == '\n':
+				e.buf.WriteString(`\n`)
+			case c == '\r':
+				e.buf.WriteString(`\r`)
+			case c == '\t':
+				e.buf.WriteString(`\t`)
+			case c < 0x20:
+				e.buf.WriteString(`\u00`)
+				e.buf.WriteByte(hexDigits[c>>4])
+				e.buf.WriteByte(hexDigits[c&0xF])
+			default:
+				e.buf.WriteByte(c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			e.buf.WriteString(`�`)
+		} else {
+			e.buf.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	e.buf.WriteByte('"')
+}
+
+func (e *encoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString(e.indent)
+	}
+}
@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+// GDataTextKey holds the text of an element when using ConventionGData
+const GDataTextKey = "$t"
+
+/*
+convertGData builds the Google Data object for el, following the shape of the JSON feeds of the GData APIs:
+  - every element becomes an object, even when it only has text or nothing at all
+  - attributes, xmlns declarations included, are keyed by their plain name
+  - the text is keyed by GDataTextKey. Mixed content is always joined
+  - child elements are keyed by their tag name, and repeated ones are collected into an array like the default rules
+  - the colon of qualified names is written as a $, so openSearch:totalResults becomes openSearch$totalResults
+  - comments, CDATA sections and processing instructions are keyed like the default rules
+
+An attribute and a child element with the same name share a key and are collected into an array.
+The convention is one way only and Reverse rejects it
+*/
+func (c *Converter) convertGData(el *ast.ElementTagNode) (ast.JsonNode, error) {
+	obj := &ast.JsonObjectNode{Token: el.Token}
+	g := newGrouper(obj)
+	for _, attr := range el.Attributes {
+		if attr == nil {
+			continue
+		}
+		g.add(attr.Key.Token, gdataName(attr.Key.Value), c.valueNode(attr.Key.Value, attr.Value.Token, attr.Value.Value))
+	}
+
+	if text, hasText := c.elementText(el); hasText {
+		g.add(el.Value.Token, GDataTextKey, c.valueNode(el.Token.Literal, el.Value.Token, text))
+	}
+
+	for _, cdata := range c.cdataSections(el) {
+		g.add(cdata.Token, CDataKey, &ast.JsonStringNode{Token: cdata.Token, Value: cdata.Value})
+	}
+	for _, m := range c.markup(el) {
+		g.add(m.Token, m.Key, m.Value)
+	}
+	for _, child := range el.Elements {
+		if child == nil {
+			continue
+		}
+		node, err := c.convertGData(child)
+		if err != nil {
+			return nil, err
+		}
+		g.add(child.Token, gdataName(child.Token.Literal), node)
+	}
+
+	return obj, nil
+}
+
+// gdataName returns the key for the xml name, writing the colon of a qualified name as a $
+func gdataName(name string) string {
+	return strings.Replace(name, ":", "$", 1)
+}
@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+// convertJsonMLDocument builds the JsonML array for the root element of doc.
+// Comments and processing instructions next to it are dropped
+func convertJsonMLDocument(doc *ast.Document) (ast.JsonNode, error) {
+	var root *ast.ElementTagNode
+	for _, el := range doc.Elements {
+		switch n := el.(type) {
+		case *ast.ElementTagNode:
+			if root != nil {
+				return nil, fmt.Errorf("a JsonML document has a single root element, found %s after %s", n.Token.Literal, root.Token.Literal)
+			}
+			root = n
+		case *ast.CommentNode, *ast.ProcInstNode:
+		default:
+			return nil, fmt.Errorf("unexpected root node %T", el)
+		}
+	}
+	if root == nil {
+		return nil, errors.New("a JsonML document needs a root element")
+	}
+	return convertJsonML(root)
+}
+
+/*
+convertJsonML builds the JsonML array for el, which is lossless for everything but markup as long as the
+text was parsed with parser.WhitespacePreserve, like the xml2json command does for this convention:
+  - the first value is the tag name
+  - the second value is an object of the attributes keyed by their name, left out when there are none
+  - every text segment, CDATA section and child element follows in document order,
+    text as a string and child elements as arrays of their own
+  - comments and processing instructions are dropped whatever the options say
+  - values are always strings, Options.InferTypes does not apply
+*/
+func convertJsonML(el *ast.ElementTagNode) (ast.JsonNode, error) {
+	arr := &ast.JsonArrayNode{Token: el.Token, Elements: []ast.JsonNode{&ast.JsonStringNode{Token: el.Token, Value: el.Token.Literal}}}
+
+	if len(el.Attributes) > 0 {
+		attrs := &ast.JsonObjectNode{Token: el.Token}
+		for _, attr := range el.Attributes {
+			if attr == nil {
+				continue
+			}
+			attrs.Members = append(attrs.Members, &ast.JsonMemberNode{
+				Token: attr.Key.Token,
+				Key:   attr.Key.Value,
+				Value: &ast.JsonStringNode{Token: attr.Value.Token, Value: attr.Value.Value},
+			})
+		}
+		arr.Elements = append(arr.Elements, attrs)
+	}
+
+	for _, child := range el.Children {
+		switch n := child.(type) {
+		case *ast.ElementValueNode:
+			arr.Elements = append(arr.Elements, &ast.JsonStringNode{Token: n.Token, Value: fmt.Sprint(n.Value)})
+		case *ast.CDataNode:
+			arr.Elements = append(arr.Elements, &ast.JsonStringNode{Token: n.Token, Value: n.Value})
+		case *ast.ElementTagNode:
+			node, err := convertJsonML(n)
+			if err != nil {
+				return nil, err
+			}
+			arr.Elements = append(arr.Elements, node)
+		case *ast.CommentNode, *ast.ProcInstNode:
+		default:
+			return nil, fmt.Errorf("unexpected child node %T in element %s", child, el.Token.Literal)
+		}
+	}
+	return arr, nil
+}
+
+// reverseJsonMLDocument builds the xml document whose root element is the JsonML array node
+func reverseJsonMLDocument(node ast.JsonNode) (*ast.Document, error) {
+	root, err := reverseJsonML(node)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Document{Elements: []ast.ElementNode{root}}, nil
+}
+
+// reverseJsonML builds the element for a JsonML array. Strings, numbers and booleans after the
+// tag name and attributes become text, arrays become child elements and null is skipped
+func reverseJsonML(node ast.JsonNode) (*ast.ElementTagNode, error) {
+	arr, ok := node.(*ast.JsonArrayNode)
+	if !ok {
+		return nil, fmt.Errorf("a JsonML element must be an array, got %T", node)
+	}
+	if len(arr.Elements) == 0 {
+		return nil, errors.New("a JsonML element must start with its tag name")
+	}
+	tag, ok := arr.Elements[0].(*ast.JsonStringNode)
+	if !ok {
+		return nil, fmt.Errorf("a JsonML element must start with its tag name, got %T", arr.Elements[0])
+	}
+	if !isQName(tag.Value) {
+		return nil, fmt.Errorf("%q is not a valid xml element name", tag.Value)
+	}
+	el := newElement(tag.Value)
+
+	content := arr.Elements[1:]
+	if len(content) > 0 {
+		if attrs, ok := content[0].(*ast.JsonObjectNode); ok {
+			for _, m := range attrs.Members {
+				if err := reverseAttribute(el, m, m.Key); err != nil {
+					return nil, err
+				}
+			}
+			content = content[1:]
+		}
+	}
+
+	for _, item := range content {
+		switch v := item.(type) {
+		case *ast.JsonArrayNode:
+			child, err := reverseJsonML(v)
+			if err != nil {
+				return nil, err
+			}
+			appendElement(el, child)
+		case *ast.JsonObjectNode:
+			return nil, fmt.Errorf("the attributes of JsonML element %s must directly follow its tag name", tag.Value)
+		case *ast.JsonNullNode:
+		default:
+			text, ok := scalarText(item)
+			if !ok {
+				return nil, fmt.Errorf("unexpected JSON node %T in JsonML element %s", item, tag.Value)
+			}
+			appendText(el, text)
+		}
+	}
+
+	closeElement(el)
+	return el, nil
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+)
+
+// isQName reports whether name is an xml name that is either unprefixed or has a single colon
+// between a non empty prefix and local name, like the parser accepts
+func isQName(name string) bool {
+	prefix, local, ok := strings.Cut(name, ":")
+	if !ok {
+		return lexer.IsName(name)
+	}
+	return lexer.IsName(prefix) && lexer.IsName(local) && !strings.Contains(local, ":")
+}
+
+// checkNamespaces reports the first element or attribute of doc whose prefix is not bound
+// by an xmlns:prefix attribute in scope. The xml prefix is always bound
+func checkNamespaces(doc *ast.Document) error {
+	for _, node := range doc.Elements {
+		if el, ok := node.(*ast.ElementTagNode); ok {
+			if err := checkElementNamespaces(el, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkElementNamespaces checks el and its children, where bound holds the prefixes declared by the ancestors of el
+func checkElementNamespaces(el *ast.ElementTagNode, bound []string) error {
+	for _, attr := range el.Attributes {
+		if name := ast.SplitName(attr.Key.Value); name.Prefix == "xmlns" {
+			bound = append(bound[:len(bound):len(bound)], name.Local)
+		}
+	}
+
+	if prefix := ast.SplitName(el.Token.Literal).Prefix; !isBound(prefix, bound) {
+		return fmt.Errorf("prefix %q of element %s is not bound by an xmlns:%s attribute", prefix, el.Token.Literal, prefix)
+	}
+	for _, attr := range el.Attributes {
+		if prefix := ast.SplitName(attr.Key.Value).Prefix; prefix != "xmlns" && !isBound(prefix, bound) {
+			return fmt.Errorf("prefix %q of attribute %s of element %s is not bound by an xmlns:%s attribute",
+				prefix, attr.Key.Value, el.Token.Literal, prefix)
+		}
+	}
+
+	for _, child := range el.Elements {
+		if err := checkElementNamespaces(child, bound); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isBound(prefix string, bound []string) bool {
+	return prefix == "" || prefix == "xml" || slices.Contains(bound, prefix)
+}
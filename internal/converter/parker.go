@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+/*
+convertParker builds the Parker value for el. The convention is lossy and keeps only what most
+consumers read, with the rules:
+  - attributes, comments and processing instructions are dropped whatever the options say
+  - an element without child elements becomes its text, CDATA included, or null when it has none
+  - any other element becomes an object keyed by the tag names of its children, and its own text is dropped
+  - sibling elements sharing a tag name are collected into an array like the default rules
+
+Parker JSON is a subset of the default mapping, so Reverse reads it back with the default rules
+*/
+func (c *Converter) convertParker(el *ast.ElementTagNode) (ast.JsonNode, error) {
+	if len(el.Elements) == 0 {
+		if el.Value.Value == nil {
+			return &ast.JsonNullNode{Token: el.Token}, nil
+		}
+		return c.valueNode(el.Token.Literal, el.Value.Token, fmt.Sprint(el.Value.Value)), nil
+	}
+
+	obj := &ast.JsonObjectNode{Token: el.Token}
+	g := newGrouper(obj)
+	for _, child := range el.Elements {
+		if child == nil {
+			continue
+		}
+		node, err := c.convertParker(child)
+		if err != nil {
+			return nil, err
+		}
+		g.add(child.Token, child.Token.Literal, node)
+	}
+	return obj, nil
+}
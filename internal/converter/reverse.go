@@ -0,0 +1,401 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+const (
+	// DefaultRootName names the element wrapping a JSON document that has no single root member
+	DefaultRootName = "root"
+
+	// ItemName names the elements created for the values of an array nested directly in another array
+	ItemName = "item"
+)
+
+/*
+Reverse builds the xml document for a JSON tree using the same rules Convert applies:
+  - members keyed by AttributePrefix + name become attributes
+  - the member keyed by TextKey becomes the element text
+  - the array keyed by ContentKey becomes mixed content in the given order
+  - every other member becomes a child element, and an array becomes one
+    repeated element per value
+  - the member keyed by CommentKey becomes one comment per string
+  - members keyed by ProcInstPrefix + target become one processing instruction per string
+  - the member keyed by CDataKey becomes one CDATA section per string
+  - null becomes an empty element
+
+An object with a single member that is an object, a scalar or null is used as the document root,
+anything else is wrapped in an element named Options.RootName. Comments and processing instructions
+next to the document root are kept outside of it.
+
+ConventionBadgerFish and ConventionJsonML are read back with their own rules, ConventionParker with the rules above,
+and ConventionGData is rejected as it can not tell attributes from elements holding only text.
+
+Every prefix of an element or attribute name has to be bound by an xmlns:prefix attribute
+on the element or one of its ancestors, like the parser requires
+*/
+func (c *Converter) Reverse(node ast.JsonNode) (*ast.Document, error) {
+	doc, err := c.reverse(node)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNamespaces(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (c *Converter) reverse(node ast.JsonNode) (*ast.Document, error) {
+	if node == nil {
+		return nil, errors.New("cannot reverse a nil JSON value")
+	}
+	switch c.opts.Convention {
+	case ConventionBadgerFish:
+		return c.reverseBadgerFish(node)
+	case ConventionGData:
+		return nil, errors.New("GData JSON can not be converted back to xml")
+	case ConventionJsonML:
+		return reverseJsonMLDocument(node)
+	}
+
+	if obj, ok := node.(*ast.JsonObjectNode); ok && hasSingleRoot(obj, c.isReservedKey) {
+		doc := &ast.Document{}
+		for _, m := range obj.Members {
+			if isMarkupKey(m.Key) {
+				nodes, err := markupNodes(m.Key, m.Value)
+				if err != nil {
+					return nil, err
+				}
+				doc.Elements = append(doc.Elements, nodes...)
+				continue
+			}
+			el, err := c.reverseElement(m.Key, m.Value)
+			if err != nil {
+				return nil, err
+			}
+			doc.Elements = append(doc.Elements, el)
+		}
+		return doc, nil
+	}
+
+	rootName := c.opts.RootName
+	if rootName == "" {
+		rootName = DefaultRootName
+	}
+
+	var root *ast.ElementTagNode
+	var err error
+	if arr, ok := node.(*ast.JsonArrayNode); ok {
+		root = newElement(rootName)
+		err = c.appendChildren(root, ItemName, arr)
+	} else {
+		root, err = c.reverseElement(rootName, node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Document{Elements: []ast.ElementNode{root}}, nil
+}
+
+// ToXml reverses node and encodes the document using the configured indent
+func (c *Converter) ToXml(node ast.JsonNode) ([]byte, error) {
+	doc, err := c.Reverse(node)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeXml(doc, c.opts.Indent), nil
+}
+
+// reverseElement builds the element called name holding value
+func (c *Converter) reverseElement(name string, value ast.JsonNode) (*ast.ElementTagNode, error) {
+	if !isQName(name) {
+		return nil, fmt.Errorf("key %q is not a valid xml element name", name)
+	}
+	el := newElement(name)
+
+	switch v := value.(type) {
+	case *ast.JsonNullNode:
+		// empty element
+	case *ast.JsonObjectNode:
+		for _, m := range v.Members {
+			if err := c.reverseMember(el, m); err != nil {
+				return nil, err
+			}
+		}
+	case *ast.JsonArrayNode:
+		if err := c.appendChildren(el, ItemName, v); err != nil {
+			return nil, err
+		}
+	default:
+		text, ok := scalarText(value)
+		if !ok {
+			return nil, fmt.Errorf("unexpected JSON node %T for element %s", value, name)
+		}
+		appendText(el, text)
+	}
+
+	closeElement(el)
+	return el, nil
+}
+
+func (c *Converter) reverseMember(el *ast.ElementTagNode, m *ast.JsonMemberNode) error {
+	switch {
+	case m.Key == c.textKey:
+		text, ok := scalarText(m.Value)
+		if !ok {
+			return fmt.Errorf("%s of element %s must be a string, number, boolean or null", c.textKey, el.Token.Literal)
+		}
+		appendText(el, text)
+	case m.Key == ContentKey:
+		arr, ok := m.Value.(*ast.JsonArrayNode)
+		if !ok {
+			return fmt.Errorf("%s of element %s must be an array", ContentKey, el.Token.Literal)
+		}
+		return c.reverseSegments(el, arr)
+	case isMarkupKey(m.Key):
+		nodes, err := markupNodes(m.Key, m.Value)
+		if err != nil {
+			return fmt.Errorf("%w in element %s", err, el.Token.Literal)
+		}
+		el.Children = append(el.Children, nodes...)
+	case m.Key == CDataKey:
+		sections, err := stringValues(CDataKey, m.Value)
+		if err != nil {
+			return fmt.Errorf("%w in element %s", err, el.Token.Literal)
+		}
+		for _, text := range sections {
+			appendCData(el, text)
+		}
+	case c.isAttributeKey(m.Key):
+		return reverseAttribute(el, m, strings.TrimPrefix(m.Key, c.attrPrefix))
+	default:
+		if arr, ok := m.Value.(*ast.JsonArrayNode); ok {
+			return c.appendChildren(el, m.Key, arr)
+		}
+		child, err := c.reverseElement(m.Key, m.Value)
+		if err != nil {
+			return err
+		}
+		appendElement(el, child)
+	}
+	return nil
+}
+
+// reverseAttribute adds the attribute called name holding the value of m to el
+func reverseAttribute(el *ast.ElementTagNode, m *ast.JsonMemberNode, name string) error {
+	if !isQName(name) {
+		return fmt.Errorf("key %q is not a valid xml attribute name", m.Key)
+	}
+	value, ok := scalarText(m.Value)
+	if !ok {
+		return fmt.Errorf("attribute %q of element %s must be a string, number, boolean or null", m.Key, el.Token.Literal)
+	}
+	el.Attributes = append(el.Attributes, newAttribute(name, value))
+	return nil
+}
+
+// appendChildren adds one element called name to el for every value in arr.
+// Arrays nested in arr become an element whose values are ItemName children
+func (c *Converter) appendChildren(el *ast.ElementTagNode, name string, arr *ast.JsonArrayNode) error {
+	for _, item := range arr.Elements {
+		child, err := c.reverseElement(name, item)
+		if err != nil {
+			return err
+		}
+		appendElement(el, child)
+	}
+	return nil
+}
+
+// reverseSegments adds the ordered mixed content produced by MixedSegments to el
+func (c *Converter) reverseSegments(el *ast.ElementTagNode, arr *ast.JsonArrayNode) error {
+	for _, segment := range arr.Elements {
+		if obj, ok := segment.(*ast.JsonObjectNode); ok {
+			if len(obj.Members) != 1 {
+				return fmt.Errorf("element segments in %s of %s must have exactly one member", ContentKey, el.Token.Literal)
+			}
+			if key := obj.Members[0].Key; isMarkupKey(key) || key == CDataKey {
+				if err := c.reverseMember(el, obj.Members[0]); err != nil {
+					return err
+				}
+				continue
+			}
+			child, err := c.reverseElement(obj.Members[0].Key, obj.Members[0].Value)
+			if err != nil {
+				return err
+			}
+			appendElement(el, child)
+			continue
+		}
+
+		text, ok := scalarText(segment)
+		if !ok {
+			return fmt.Errorf("unexpected segment %T in %s of %s", segment, ContentKey, el.Token.Literal)
+		}
+		appendText(el, text)
+	}
+	return nil
+}
+
+// hasSingleRoot reports whether obj has exactly one member, besides comments,
+// that can be used as the document root. Members whose key is reserved can not
+func hasSingleRoot(obj *ast.JsonObjectNode, reserved func(string) bool) bool {
+	var root *ast.JsonMemberNode
+	for _, m := range obj.Members {
+		if isMarkupKey(m.Key) {
+			continue
+		}
+		if root != nil {
+			return false
+		}
+		root = m
+	}
+	if root == nil || reserved(root.Key) {
+		return false
+	}
+	_, isArray := root.Value.(*ast.JsonArrayNode)
+	return !isArray
+}
+
+// markupNodes returns the comments or processing instructions held by a member keyed by
+// CommentKey or ProcInstPrefix + target
+func markupNodes(key string, value ast.JsonNode) ([]ast.ElementNode, error) {
+	values, err := stringValues(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []ast.ElementNode
+	for _, v := range values {
+		if key == CommentKey {
+			if strings.Contains(v, "--") || strings.HasSuffix(v, "-") {
+				return nil, fmt.Errorf("%s %q can not be written as an xml comment", CommentKey, v)
+			}
+			nodes = append(nodes, &ast.CommentNode{Token: token.Token{Type: token.COMMENT, Literal: v}, Value: v})
+			continue
+		}
+
+		target := strings.TrimPrefix(key, ProcInstPrefix)
+		if !lexer.IsName(target) || strings.EqualFold(target, "xml") {
+			return nil, fmt.Errorf("key %q is not a valid processing instruction target", key)
+		}
+		if strings.Contains(v, "?>") {
+			return nil, fmt.Errorf("%s %q can not be written as a processing instruction", key, v)
+		}
+		literal := target
+		if v != "" {
+			literal += " " + v
+		}
+		nodes = append(nodes, &ast.ProcInstNode{Token: token.Token{Type: token.PI, Literal: literal}, Target: target, Data: v})
+	}
+	return nodes, nil
+}
+
+// stringValues returns the strings held by the member keyed by key, either a single string or an array of strings
+func stringValues(key string, value ast.JsonNode) ([]string, error) {
+	values := []ast.JsonNode{value}
+	if arr, ok := value.(*ast.JsonArrayNode); ok {
+		values = arr.Elements
+	}
+
+	var strs []string
+	for _, v := range values {
+		s, ok := v.(*ast.JsonStringNode)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string or an array of strings", key)
+		}
+		strs = append(strs, s.Value)
+	}
+	return strs, nil
+}
+
+func newElement(name string) *ast.ElementTagNode {
+	return &ast.ElementTagNode{Token: token.Token{Type: token.TAG, Literal: name}, Name: ast.SplitName(name)}
+}
+
+func newAttribute(name, value string) *ast.ElementAttributeNode {
+	return &ast.ElementAttributeNode{
+		Key:   &ast.AttributeKeyNode{Token: token.Token{Type: token.KEY, Literal: name}, Value: name, Name: ast.SplitName(name)},
+		Value: &ast.AttributeValueNode{Token: token.Token{Type: token.VALUE, Literal: value}, Value: value},
+	}
+}
+
+// appendText adds a text segment to el. closeElement joins it into the element value like the parser does
+func appendText(el *ast.ElementTagNode, text string) {
+	el.Children = append(el.Children, &ast.ElementValueNode{Token: token.Token{Type: token.VALUE, Literal: text}, Value: text})
+}
+
+// appendCData adds a CDATA section to el. closeElement joins its text into the element value like the parser does
+func appendCData(el *ast.ElementTagNode, text string) {
+	el.Children = append(el.Children, &ast.CDataNode{Token: token.Token{Type: token.CDATA, Literal: text}, Value: text})
+}
+
+func appendElement(el, child *ast.ElementTagNode) {
+	el.Elements = append(el.Elements, child)
+	el.Children = append(el.Children, child)
+}
+
+// closeElement joins the text of el and sets the EndToken the parser would produce for it
+func closeElement(el *ast.ElementTagNode) {
+	el.JoinText()
+	if len(el.Children) == 0 {
+		el.EndToken = token.Token{Type: token.CLOSE_ANGLE, Literal: token.CLOSE_ANGLE}
+	} else {
+		el.EndToken = el.Token
+	}
+}
+
+// scalarText returns the xml text of a string, number, boolean or null value.
+// Numbers keep the exact text they were written with, so no precision is lost
+func scalarText(node ast.JsonNode) (string, bool) {
+	switch n := node.(type) {
+	case *ast.JsonStringNode:
+		return n.Value, true
+	case *ast.JsonNumberNode:
+		if n.Token.Literal != "" {
+			return n.Token.Literal, true
+		}
+		switch v := n.Value.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10), true
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), true
+		default:
+			return n.Token.Literal, true
+		}
+	case *ast.JsonBoolNode:
+		return strconv.FormatBool(n.Value), true
+	case *ast.JsonNullNode:
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// isReservedKey reports whether key is read back as something other than an element
+func (c *Converter) isReservedKey(key string) bool {
+	return c.isSpecialKey(key) || c.isAttributeKey(key)
+}
+
+// isSpecialKey reports whether key holds the text, content, CDATA sections or markup of an element
+func (c *Converter) isSpecialKey(key string) bool {
+	return key == c.textKey || key == ContentKey || key == CDataKey || isMarkupKey(key)
+}
+
+// isAttributeKey reports whether key starts with the attribute prefix. Special keys are matched first,
+// so a key like "_text" is not read as an attribute when it is also the text key
+func (c *Converter) isAttributeKey(key string) bool {
+	return c.attrPrefix != "" && strings.HasPrefix(key, c.attrPrefix)
+}
+
+// isMarkupKey reports whether key holds comments or processing instructions
+func isMarkupKey(key string) bool {
+	return key == CommentKey || strings.HasPrefix(key, ProcInstPrefix)
+}
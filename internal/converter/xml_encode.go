@@ -0,0 +1,164 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;", "\t", "&#9;", "\n", "&#10;", "\r", "&#13;")
+)
+
+// EncodeXml writes doc out as xml text.
+// When indent is empty the output is compact, otherwise every element is placed on its own line
+// and prefixed with indent once per level. Elements with mixed content are never indented
+// since that would change their text.
+// The output is always UTF-8, so the encoding of a declaration is written as UTF-8.
+// A DOCTYPE is written exactly as it was parsed
+func EncodeXml(doc *ast.Document, indent string) []byte {
+	var buf bytes.Buffer
+	e := &xmlEncoder{buf: &buf, indent: indent}
+	if doc.Declaration != nil {
+		e.writeDeclaration(doc.Declaration)
+	}
+	if doc.Doctype != nil {
+		if doc.Declaration != nil && indent != "" {
+			buf.WriteByte('\n')
+		}
+		e.writeDoctype(doc.Doctype)
+	}
+	for i, el := range doc.Elements {
+		if (i > 0 || doc.Declaration != nil || doc.Doctype != nil) && indent != "" {
+			buf.WriteByte('\n')
+		}
+		switch n := el.(type) {
+		case *ast.ElementTagNode:
+			e.writeElement(n, 0, indent != "")
+		case *ast.CommentNode:
+			e.writeComment(n)
+		case *ast.ProcInstNode:
+			e.writeProcInst(n)
+		}
+	}
+	return buf.Bytes()
+}
+
+type xmlEncoder struct {
+	buf    *bytes.Buffer
+	indent string
+}
+
+func (e *xmlEncoder) writeElement(el *ast.ElementTagNode, depth int, pretty bool) {
+	e.buf.WriteByte('<')
+	e.buf.WriteString(el.Token.Literal)
+	for _, attr := range el.Attributes {
+		if attr == nil {
+			continue
+		}
+		e.buf.WriteByte(' ')
+		e.buf.WriteString(attr.Key.Value)
+		e.buf.WriteString(`="`)
+		attrEscaper.WriteString(e.buf, attr.Value.Value)
+		e.buf.WriteByte('"')
+	}
+
+	if len(el.Children) == 0 {
+		e.buf.WriteString("/>")
+		return
+	}
+	e.buf.WriteByte('>')
+
+	// only element, comment and processing instruction children are placed on their own lines
+	pretty = pretty && !hasText(el)
+	for _, child := range el.Children {
+		switch n := child.(type) {
+		case *ast.ElementTagNode:
+			e.newline(depth+1, pretty)
+			e.writeElement(n, depth+1, pretty)
+		case *ast.CommentNode:
+			e.newline(depth+1, pretty)
+			e.writeComment(n)
+		case *ast.ProcInstNode:
+			e.newline(depth+1, pretty)
+			e.writeProcInst(n)
+		case *ast.ElementValueNode:
+			textEscaper.WriteString(e.buf, fmt.Sprint(n.Value))
+		case *ast.CDataNode:
+			e.writeCData(n)
+		}
+	}
+	e.newline(depth, pretty)
+
+	e.buf.WriteString("</")
+	e.buf.WriteString(el.Token.Literal)
+	e.buf.WriteByte('>')
+}
+
+func (e *xmlEncoder) writeComment(c *ast.CommentNode) {
+	e.buf.WriteString("<!--")
+	e.buf.WriteString(c.Value)
+	e.buf.WriteString("-->")
+}
+
+func (e *xmlEncoder) writeDeclaration(d *ast.Declaration) {
+	e.buf.WriteString(`<?xml version="`)
+	e.buf.WriteString(d.Version)
+	e.buf.WriteByte('"')
+	if d.Encoding != "" {
+		e.buf.WriteString(` encoding="UTF-8"`)
+	}
+	if d.Standalone != "" {
+		e.buf.WriteString(` standalone="`)
+		e.buf.WriteString(d.Standalone)
+		e.buf.WriteByte('"')
+	}
+	e.buf.WriteString("?>")
+}
+
+func (e *xmlEncoder) writeDoctype(d *ast.Doctype) {
+	e.buf.WriteString("<!DOCTYPE")
+	e.buf.WriteString(d.Token.Literal)
+	e.buf.WriteByte('>')
+}
+
+func (e *xmlEncoder) writeProcInst(p *ast.ProcInstNode) {
+	e.buf.WriteString("<?")
+	e.buf.WriteString(p.Target)
+	if p.Data != "" {
+		e.buf.WriteByte(' ')
+		e.buf.WriteString(p.Data)
+	}
+	e.buf.WriteString("?>")
+}
+
+// writeCData writes c as a CDATA section. A "]]>" in the text is split across two sections
+func (e *xmlEncoder) writeCData(c *ast.CDataNode) {
+	e.buf.WriteString("<![CDATA[")
+	e.buf.WriteString(strings.ReplaceAll(c.Value, "]]>", "]]]]><![CDATA[>"))
+	e.buf.WriteString("]]>")
+}
+
+func (e *xmlEncoder) newline(depth int, pretty bool) {
+	if !pretty {
+		return
+	}
+	e.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString(e.indent)
+	}
+}
+
+// hasText reports whether any of the children of el is text or CDATA
+func hasText(el *ast.ElementTagNode) bool {
+	for _, child := range el.Children {
+		switch child.(type) {
+		case *ast.ElementValueNode, *ast.CDataNode:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package lexer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUtf8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUtf16BE = []byte{0xFE, 0xFF}
+	bomUtf16LE = []byte{0xFF, 0xFE}
+)
+
+// windows1252 maps the bytes 0x80 to 0x9F of windows-1252 that differ from ISO-8859-1
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// declarationPeek is how much of the input is searched for an xml declaration naming the encoding
+const declarationPeek = 1024
+
+/*
+Decode converts raw xml input into the UTF-8 text read by an XML lexer.
+The encoding is taken from a byte order mark, or else from the encoding of
+the <?xml ...?> declaration, and defaults to UTF-8. Supported encodings are
+UTF-8, UTF-16 (with a byte order mark), US-ASCII, ISO-8859-1 and windows-1252.
+A UTF-8 byte order mark is dropped
+*/
+func Decode(input []byte) (string, error) {
+	r, err := NewDecoder(bytes.NewReader(input))
+	if err != nil {
+		return "", err
+	}
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+/*
+NewDecoder returns a reader of the UTF-8 text of the xml read from r, decoded as Decode would.
+The encoding is chosen from the start of the input, and input that turns out not to be valid in it
+is reported by Read. Use it with NewReader to lex xml in any supported encoding without reading it all first
+*/
+func NewDecoder(r io.Reader) (io.Reader, error) {
+	src := bufio.NewReaderSize(r, declarationPeek)
+	head, err := src.Peek(declarationPeek)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	d := &decoder{src: src}
+	switch {
+	case bytes.HasPrefix(head, bomUtf8):
+		src.Discard(len(bomUtf8))
+		head = head[len(bomUtf8):]
+	case bytes.HasPrefix(head, bomUtf16BE):
+		src.Discard(len(bomUtf16BE))
+		d.decode, d.bigEndian = d.readUtf16, true
+		return d, nil
+	case bytes.HasPrefix(head, bomUtf16LE):
+		src.Discard(len(bomUtf16LE))
+		d.decode = d.readUtf16
+		return d, nil
+	}
+
+	encoding := declaredEncoding(head)
+	switch strings.ToUpper(encoding) {
+	case "", "UTF-8", "UTF8":
+		d.decode = d.readUtf8
+	case "US-ASCII", "ASCII":
+		d.decode = d.readAscii
+	case "ISO-8859-1", "ISO_8859-1", "LATIN1", "L1":
+		d.decode = d.readSingleByte
+	case "WINDOWS-1252", "CP1252":
+		d.decode, d.high = d.readSingleByte, &windows1252
+	case "UTF-16", "UTF-16BE", "UTF-16LE":
+		return nil, fmt.Errorf("%s input must start with a byte order mark", encoding)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return d, nil
+}
+
+// declaredEncoding returns the value of the encoding pseudo attribute of an xml declaration
+// at the start of input, or "" if there is none
+func declaredEncoding(input []byte) string {
+	if !bytes.HasPrefix(input, []byte("<?xml")) || len(input) < 6 || !isSpace(rune(input[5])) {
+		return ""
+	}
+	end := bytes.Index(input, []byte("?>"))
+	if end < 0 {
+		return ""
+	}
+	decl := string(input[5:end])
+
+	i := strings.Index(decl, "encoding")
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(decl[i+len("encoding"):], " \t\r\n")
+	if !strings.HasPrefix(rest, "=") {
+		return ""
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+		return ""
+	}
+	value, _, found := strings.Cut(rest[1:], rest[:1])
+	if !found {
+		return ""
+	}
+	return value
+}
+
+// decoder reads the runes of an encoded input one at a time and writes them out as UTF-8
+type decoder struct {
+	src    *bufio.Reader
+	decode func() (rune, error)
+	offset int    // bytes read from src, for error messages
+	buf    []byte // the UTF-8 of a rune that did not fit into the last Read
+	err    error
+
+	bigEndian bool      // byte order of UTF-16 input
+	high      *[32]rune // the chars of the bytes 0x80 to 0x9F in a single byte encoding other than ISO-8859-1
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+
+	for n < len(p) && d.err == nil {
+		var r rune
+		if r, d.err = d.decode(); d.err != nil {
+			break
+		}
+		if utf8.RuneLen(r) > len(p)-n {
+			d.buf = utf8.AppendRune(nil, r)
+			copied := copy(p[n:], d.buf)
+			d.buf = d.buf[copied:]
+			n += copied
+			break
+		}
+		n += utf8.EncodeRune(p[n:], r)
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+func (d *decoder) readUtf8() (rune, error) {
+	r, size, err := d.src.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r == utf8.RuneError && size == 1 {
+		return 0, fmt.Errorf("input is not valid UTF-8")
+	}
+	d.offset += size
+	return r, nil
+}
+
+func (d *decoder) readAscii() (rune, error) {
+	b, err := d.src.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b >= utf8.RuneSelf {
+		return 0, fmt.Errorf("byte 0x%X at offset %d is not valid US-ASCII", b, d.offset)
+	}
+	d.offset++
+	return rune(b), nil
+}
+
+// readSingleByte decodes a single byte encoding that matches ISO-8859-1 except
+// for the bytes 0x80 to 0x9F, which are looked up in high when it is not nil
+func (d *decoder) readSingleByte() (rune, error) {
+	b, err := d.src.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.offset++
+	if d.high != nil && b >= 0x80 && b <= 0x9F {
+		return d.high[b-0x80], nil
+	}
+	return rune(b), nil
+}
+
+// readUtf16 decodes a UTF-16 code unit, or a surrogate pair of them. An unpaired surrogate is read as utf8.RuneError
+func (d *decoder) readUtf16() (rune, error) {
+	r, err := d.readUnit()
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+
+	if next, err := d.src.Peek(2); err == nil {
+		if pair := utf16.DecodeRune(r, d.unit(next)); pair != utf8.RuneError {
+			d.src.Discard(2)
+			d.offset += 2
+			return pair, nil
+		}
+	}
+	return utf8.RuneError, nil
+}
+
+func (d *decoder) readUnit() (rune, error) {
+	b, err := d.src.Peek(2)
+	switch {
+	case len(b) == 1:
+		return 0, fmt.Errorf("UTF-16 input has an odd number of bytes")
+	case err != nil:
+		return 0, err
+	}
+	d.src.Discard(2)
+	d.offset += 2
+	return d.unit(b), nil
+}
+
+// unit returns the UTF-16 code unit in the first two bytes of b
+func (d *decoder) unit(b []byte) rune {
+	if d.bigEndian {
+		return rune(b[0])<<8 | rune(b[1])
+	}
+	return rune(b[1])<<8 | rune(b[0])
+}
+
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
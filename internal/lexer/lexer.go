@@ -2,6 +2,11 @@ package lexer
 
 import (
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/jdodson3106/goXml2Json/internal/token"
 )
@@ -11,13 +16,27 @@ const (
 	XML  = "xml"
 )
 
+// readerChunk is the most a Lexer created by NewReader reads at once while its buffered input is small,
+// and how much input it keeps before dropping the part already lexed
+const readerChunk = 32 << 10
+
 type Lexer struct {
 	lexType         string
-	input           string
-	currentPosition int // current char in the input
-	nextPosition    int // next position in the input
-	lastRead        byte
-	ch              byte // current char being read
+	input           string    // the whole input, or the buffered part of it when lexing from a reader
+	reader          io.Reader // the rest of the input, nil once it has all been read into input
+	readBuf         []byte    // reused buffer for reads from reader
+	err             error     // the error that ended reading from reader early, if any
+	base            int       // byte offset of input in the whole input, dropped from the front of the buffer
+	currentPosition int       // byte offset of the current char in the input
+	nextPosition    int       // byte offset of the next char in the input
+	lastRead        rune
+	lastToken       token.Token    // the token most recently returned by NextToken
+	quote           rune           // the quote opening the attribute value about to be read, if any
+	tokenPos        token.Position // position of the first char of the token being read
+	char            int            // char offset of the current char
+	line            int            // line of the current char
+	column          int            // column of the current char, counted in chars
+	ch              rune           // current char being read
 }
 
 func New(input, lexType string) (*Lexer, error) {
@@ -25,26 +44,117 @@ func New(input, lexType string) (*Lexer, error) {
 		return nil, fmt.Errorf("invalid lexer type %s", lexType)
 	}
 
-	l := &Lexer{input: input, lexType: lexType}
+	l := &Lexer{input: input, lexType: lexType, line: 1, char: -1}
+	l.readChar()
+	return l, nil
+}
+
+/*
+NewReader creates a lexer reading UTF-8 input from r as it goes, producing the same tokens as New would for
+the whole input. Only the input of the token being read and a small buffer are held in memory, so memory
+use does not grow with the size of the input. XML in other encodings can be read through NewDecoder.
+An error reading from r ends the input early and is returned by Err
+*/
+func NewReader(r io.Reader, lexType string) (*Lexer, error) {
+	if lexType != JSON && lexType != XML {
+		return nil, fmt.Errorf("invalid lexer type %s", lexType)
+	}
+
+	l := &Lexer{reader: r, lexType: lexType, line: 1, char: -1}
 	l.readChar()
 	return l, nil
 }
 
+// Err returns the error that ended reading the input of a lexer created by NewReader early, if any
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// fill reads from the reader until the input holds at least n bytes or the reader is done
+func (l *Lexer) fill(n int) {
+	for l.reader != nil && len(l.input) < n {
+		// read bigger chunks as the buffer grows for a long token so appending stays linear
+		size := max(readerChunk, len(l.input))
+		if len(l.readBuf) < size {
+			l.readBuf = make([]byte, size)
+		}
+
+		// a whole chunk is read at once since every read copies the buffered input
+		read, err := io.ReadFull(l.reader, l.readBuf[:size])
+		l.input += string(l.readBuf[:read])
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				l.err = err
+			}
+			l.reader, l.readBuf = nil, nil
+		}
+	}
+}
+
+// compact drops the input before the current char once enough of it has been lexed.
+// It is only called between tokens so offsets taken while reading a token stay valid
+func (l *Lexer) compact() {
+	if l.currentPosition < readerChunk || l.currentPosition > len(l.input) {
+		return
+	}
+	l.base += l.currentPosition
+	l.input = l.input[l.currentPosition:]
+	l.nextPosition -= l.currentPosition
+	l.currentPosition = 0
+}
+
+// index returns the offset of s in the input from from on, reading more input as needed, or -1 if there is none
+func (l *Lexer) index(from int, s string) int {
+	searched := min(from, len(l.input))
+	for {
+		if i := strings.Index(l.input[searched:], s); i >= 0 {
+			return searched + i - from
+		}
+		if l.reader == nil {
+			return -1
+		}
+		// the next read can complete a match that started at the end of the input so far
+		searched = max(searched, len(l.input)-len(s)+1)
+		l.fill(len(l.input) + 1)
+	}
+}
+
 func (l *Lexer) readChar() {
+	l.fill(l.nextPosition + utf8.UTFMax)
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+	l.char++
+
+	// chars are decoded from utf-8, invalid bytes are read one at a time as utf8.RuneError
+	width := 1
 	if l.nextPosition >= len(l.input) {
 		l.ch = 0 // set the current char to 0 (ASCII NULL value)
 	} else {
 		l.lastRead = l.ch
-		l.ch = l.input[l.nextPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.nextPosition:])
 	}
 	l.currentPosition = l.nextPosition
-	l.nextPosition++
+	l.nextPosition += width
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var t token.Token
 
+	l.compact()
+	if l.lexType == XML {
+		l.tokenPos = l.position()
+		if t, ok := l.readXmlValue(); ok {
+			t.Pos = l.tokenPos
+			l.lastToken = t
+			return t
+		}
+	}
+
 	l.eatWhitespace()
+	l.tokenPos = l.position()
 
 	if l.lexType == JSON {
 		t = l.nextJsonToken()
@@ -69,8 +179,10 @@ func (l *Lexer) NextToken() token.Token {
 			t.Literal = ""
 			t.Type = token.EOF
 		default:
-			if isAlphaNumeric(l.ch) {
+			if IsNameStartChar(l.ch) {
 				t = l.readIdentifier()
+				t.Pos = l.tokenPos
+				l.lastToken = t
 				return t
 			} else {
 				t = newToken(token.ILLEGAL, l.ch)
@@ -79,6 +191,8 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	t.Pos = l.tokenPos
+	l.lastToken = t
 	return t
 }
 
@@ -87,16 +201,82 @@ func (l *Lexer) nextXmlToken() token.Token {
 
 	switch l.ch {
 	case '<':
+		if l.peekString("!--") {
+			return l.readMarkup(token.COMMENT, "<!--", "-->", "comment")
+		}
+		if l.peekString("![CDATA[") {
+			return l.readMarkup(token.CDATA, "<![CDATA[", "]]>", "CDATA section")
+		}
+		if l.peekString("!DOCTYPE") {
+			return l.readDoctype()
+		}
+		if l.peekChar() == '?' {
+			return l.readMarkup(token.PI, "<?", "?>", "processing instruction")
+		}
 		t = newToken(token.OPEN_ANGLE, l.ch)
 	case '>':
 		t = newToken(token.CLOSE_ANGLE, l.ch)
 	case '/':
 		t = newToken(token.XML_TERMINATOR, l.ch)
+	case '"', '\'':
+		// a quote following '=' opens an attribute value
+		if l.lastToken.Type == token.EQUAL {
+			l.quote = l.ch
+		}
 	}
 
 	return t
 }
 
+/*
+readXmlValue - reads the text of an attribute value after its opening quote, or the character data
+following a '>'. Both are kept exactly as written, references and all. An attribute value ends at its
+closing quote and character data ends at the next '<'. Character data that is only whitespace, like
+indentation, is a VALUE as well so the parser can decide whether it matters. Any other state reports false
+*/
+func (l *Lexer) readXmlValue() (token.Token, bool) {
+	if l.quote == 0 && !l.isCharData() {
+		return token.Token{}, false
+	}
+
+	// both kinds of value end at the next '<' at the latest
+	lt := l.index(l.currentPosition, "<")
+	var rest string
+	if l.currentPosition < len(l.input) {
+		rest = l.input[l.currentPosition:]
+	}
+	if lt < 0 {
+		lt = len(rest)
+	}
+
+	end := lt
+	if l.quote != 0 {
+		// a value that is not closed before the next tag ends at the other kind of quote
+		// if there is one, so the parser can report the mismatch, and otherwise at the '<'
+		if end = strings.IndexRune(rest[:lt], l.quote); end < 0 {
+			if end = strings.IndexAny(rest[:lt], `"'`); end < 0 {
+				end = lt
+			}
+		}
+		l.quote = 0
+	}
+
+	for stop := l.currentPosition + end; l.currentPosition < stop; {
+		l.readChar()
+	}
+	return token.Token{Type: token.VALUE, Literal: rest[:end]}, true
+}
+
+// isCharData reports whether the lexer is between markup, where everything up to the next '<' is text
+func (l *Lexer) isCharData() bool {
+	switch l.lastToken.Type {
+	case token.CLOSE_ANGLE, token.COMMENT, token.CDATA, token.PI, token.DOCTYPE:
+		return l.ch != '<' && l.currentPosition < len(l.input)
+	default:
+		return false
+	}
+}
+
 func (l *Lexer) nextJsonToken() token.Token {
 	var t token.Token
 
@@ -109,36 +289,227 @@ func (l *Lexer) nextJsonToken() token.Token {
 		t = newToken(token.OPEN_SQUARE, l.ch)
 	case ']':
 		t = newToken(token.CLOSE_SQUARE, l.ch)
+	case '"':
+		t = l.readJsonString()
+	default:
+		if l.ch == '-' || isDigit(l.ch) {
+			t = l.readJsonNumber()
+		} else if isLetter(l.ch) {
+			t = l.readJsonKeyword()
+		}
 	}
 	return t
 }
 
 /*
-readIdentifier - determines is the current read is a TAG, KEY, or VALUE
-and reads the value into the appropriate TokenType
+readJsonString - reads a quoted json string and unescapes it into the token literal.
+The lexer is left on the closing quote
+*/
+func (l *Lexer) readJsonString() token.Token {
+	var builder strings.Builder
+
+	for {
+		l.readChar()
+		switch {
+		case l.ch == '"':
+			return token.Token{Type: token.STRING, Literal: builder.String()}
+		case l.ch == 0 && l.currentPosition >= len(l.input):
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated string"}
+		case l.ch < 0x20:
+			return token.Token{Type: token.ILLEGAL, Literal: "control character in string"}
+		case l.ch == '\\':
+			if !l.readJsonEscape(&builder) {
+				// skip the rest of the string so lexing resumes after the closing quote
+				for l.ch != '"' && l.ch != 0 {
+					l.readChar()
+				}
+				return token.Token{Type: token.ILLEGAL, Literal: "invalid escape sequence in string"}
+			}
+		default:
+			builder.WriteRune(l.ch)
+		}
+	}
+}
+
+// readJsonEscape writes the character escaped after a backslash to builder
+func (l *Lexer) readJsonEscape(builder *strings.Builder) bool {
+	l.readChar()
+	switch l.ch {
+	case '"', '\\', '/':
+		builder.WriteRune(l.ch)
+	case 'b':
+		builder.WriteByte('\b')
+	case 'f':
+		builder.WriteByte('\f')
+	case 'n':
+		builder.WriteByte('\n')
+	case 'r':
+		builder.WriteByte('\r')
+	case 't':
+		builder.WriteByte('\t')
+	case 'u':
+		r, ok := l.readHexRune()
+		if !ok {
+			return false
+		}
+
+		// characters outside the basic multilingual plane are written as a utf-16 surrogate pair
+		if utf16.IsSurrogate(r) && l.peekChar() == '\\' && l.peekCharAt(1) == 'u' {
+			l.readChar()
+			l.readChar()
+			low, ok := l.readHexRune()
+			if !ok {
+				return false
+			}
+			r = utf16.DecodeRune(r, low)
+		}
+		builder.WriteRune(r)
+	default:
+		return false
+	}
+	return true
+}
+
+// readHexRune reads the four hex digits of a \u escape
+func (l *Lexer) readHexRune() (rune, bool) {
+	if l.fill(l.nextPosition + 4); l.nextPosition+4 > len(l.input) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(l.input[l.nextPosition:l.nextPosition+4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	for i := 0; i < 4; i++ {
+		l.readChar()
+	}
+	return rune(v), true
+}
+
+/*
+readJsonNumber - reads a json number into an INT or FLOAT token.
+The lexer is left on the last char of the number
+*/
+func (l *Lexer) readJsonNumber() token.Token {
+	pos := l.currentPosition
+	tokType := token.TokenType(token.INT)
+
+	for isNumberChar(l.peekChar()) {
+		l.readChar()
+		if l.ch == '.' || l.ch == 'e' || l.ch == 'E' {
+			tokType = token.FLOAT
+		}
+	}
+
+	literal := l.input[pos:l.nextPosition]
+	if !isJsonNumber(literal) {
+		return token.Token{Type: token.ILLEGAL, Literal: literal}
+	}
+	return token.Token{Type: tokType, Literal: literal}
+}
+
+/*
+readJsonKeyword - reads true, false or null.
+The lexer is left on the last char of the word
+*/
+func (l *Lexer) readJsonKeyword() token.Token {
+	pos := l.currentPosition
+	for isLetter(l.peekChar()) {
+		l.readChar()
+	}
+
+	literal := l.input[pos:l.nextPosition]
+	return token.Token{Type: token.LookupKeyword(literal), Literal: literal}
+}
+
+/*
+readMarkup - reads markup like <!-- comment -->, <![CDATA[ text ]]> or <?target data?> into a tokType token holding
+the text between opening and closing exactly as written. The lexer is left on the last char of closing
+*/
+func (l *Lexer) readMarkup(tokType token.TokenType, opening, closing, name string) token.Token {
+	// skip over the opening, the lexer is on its first char
+	for i := 1; i < len(opening); i++ {
+		l.readChar()
+	}
+
+	start := l.nextPosition
+	end := l.index(start, closing)
+	if end < 0 {
+		for l.ch != 0 {
+			l.readChar()
+		}
+		return token.Token{Type: token.ILLEGAL, Literal: "unterminated " + name}
+	}
+
+	for l.nextPosition < start+end+len(closing) {
+		l.readChar()
+	}
+	return token.Token{Type: tokType, Literal: l.input[start : start+end]}
+}
+
+/*
+readDoctype - reads a <!DOCTYPE ...> into a DOCTYPE token holding the text after the keyword.
+A '>' inside the [ ] internal subset, a quoted string or a comment does not end the declaration.
+The lexer is left on the final '>'
+*/
+func (l *Lexer) readDoctype() token.Token {
+	for i := 0; i < len("!DOCTYPE"); i++ {
+		l.readChar()
+	}
+
+	start := l.nextPosition
+	var quote rune
+	inSubset := false
+	for {
+		l.readChar()
+		switch {
+		case l.ch == 0 && l.currentPosition >= len(l.input):
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated DOCTYPE"}
+		case quote != 0:
+			if l.ch == quote {
+				quote = 0
+			}
+		case l.ch == '"' || l.ch == '\'':
+			quote = l.ch
+		case l.ch == '[':
+			inSubset = true
+		case l.ch == ']':
+			inSubset = false
+		case inSubset && l.ch == '<' && l.peekString("!--"):
+			end := l.index(l.nextPosition, "-->")
+			if end < 0 {
+				for l.ch != 0 {
+					l.readChar()
+				}
+				return token.Token{Type: token.ILLEGAL, Literal: "unterminated DOCTYPE"}
+			}
+			for stop := l.nextPosition + end + 2; l.currentPosition < stop; {
+				l.readChar()
+			}
+		case !inSubset && l.ch == '>':
+			return token.Token{Type: token.DOCTYPE, Literal: l.input[start:l.currentPosition]}
+		}
+	}
+}
+
+/*
+readIdentifier - reads the name of a TAG or of an attribute KEY made of NameChars.
+Text and attribute values are read by readXmlValue
 */
 func (l *Lexer) readIdentifier() token.Token {
 	var tok token.Token
-	lr := string(l.lastRead)
 
-	switch lr {
+	switch string(l.lastRead) {
 	case token.OPEN_ANGLE:
 		tok.Type = token.TAG
-	case token.QUOTE:
-		fallthrough
-	case token.CLOSE_ANGLE:
-		tok.Type = token.VALUE
 	case token.XML_TERMINATOR:
-		next := string(l.input[l.nextPosition])
-		if next != token.CLOSE_ANGLE {
-			tok.Type = token.TAG
-		}
+		// the name of a closing tag like </tag>
+		tok.Type = token.TAG
 	default:
 		tok.Type = token.KEY
 	}
 
 	pos := l.currentPosition
-	for isAlphaNumeric(l.ch) {
+	for IsNameChar(l.ch) {
 		l.readChar()
 	}
 
@@ -146,17 +517,91 @@ func (l *Lexer) readIdentifier() token.Token {
 	return tok
 }
 
+// peekString reports whether the chars following the current one are s
+func (l *Lexer) peekString(s string) bool {
+	if l.fill(l.nextPosition + len(s)); l.nextPosition > len(l.input) {
+		return false
+	}
+	return strings.HasPrefix(l.input[l.nextPosition:], s)
+}
+
+// position returns the position of the current char
+func (l *Lexer) position() token.Position {
+	return token.Position{Offset: l.base + l.currentPosition, Char: l.char, Line: l.line, Column: l.column}
+}
+
+func (l *Lexer) peekChar() rune {
+	if l.fill(l.nextPosition + utf8.UTFMax); l.nextPosition >= len(l.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.nextPosition:])
+	return r
+}
+
+// peekCharAt returns the byte n bytes after the next char without consuming anything
+func (l *Lexer) peekCharAt(n int) byte {
+	if l.fill(l.nextPosition + n + 1); l.nextPosition+n >= len(l.input) {
+		return 0
+	}
+	return l.input[l.nextPosition+n]
+}
+
 func (l *Lexer) eatWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for isSpace(l.ch) {
 		l.readChar()
 	}
 }
 
-func newToken(tokenType token.TokenType, char byte) token.Token {
+func newToken(tokenType token.TokenType, char rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(char)}
 }
 
-func isAlphaNumeric(ch byte) bool {
-	isAN := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '-' || ch == '.'
-	return isAN
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isLetter(ch rune) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNumberChar(ch rune) bool {
+	return isDigit(ch) || ch == '-' || ch == '+' || ch == '.' || ch == 'e' || ch == 'E'
+}
+
+// isJsonNumber validates literal against the json number grammar
+// -?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?
+func isJsonNumber(literal string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(literal) && isDigit(rune(literal[i])) {
+			i++
+		}
+		return i - start
+	}
+
+	if i < len(literal) && literal[i] == '-' {
+		i++
+	}
+	if i < len(literal) && literal[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(literal) && literal[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(literal) && (literal[i] == 'e' || literal[i] == 'E') {
+		i++
+		if i < len(literal) && (literal[i] == '+' || literal[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(literal)
 }
@@ -0,0 +1,41 @@
+package lexer
+
+// IsNameStartChar reports whether r can start an xml name, following the NameStartChar production of the xml spec
+func IsNameStartChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		return true
+	case r >= 0xC0 && r <= 0xD6, r >= 0xD8 && r <= 0xF6, r >= 0xF8 && r <= 0x2FF:
+		return true
+	case r >= 0x370 && r <= 0x37D, r >= 0x37F && r <= 0x1FFF:
+		return true
+	case r >= 0x200C && r <= 0x200D, r >= 0x2070 && r <= 0x218F, r >= 0x2C00 && r <= 0x2FEF:
+		return true
+	case r >= 0x3001 && r <= 0xD7FF, r >= 0xF900 && r <= 0xFDCF, r >= 0xFDF0 && r <= 0xFFFD:
+		return true
+	default:
+		return r >= 0x10000 && r <= 0xEFFFF
+	}
+}
+
+// IsNameChar reports whether r can appear in an xml name after its first char, following the NameChar production
+func IsNameChar(r rune) bool {
+	switch {
+	case IsNameStartChar(r):
+		return true
+	case r >= '0' && r <= '9', r == '-', r == '.', r == 0xB7:
+		return true
+	default:
+		return (r >= 0x300 && r <= 0x36F) || (r >= 0x203F && r <= 0x2040)
+	}
+}
+
+// IsName reports whether s is a valid xml name like tag, ns:tag or größe
+func IsName(s string) bool {
+	for i, r := range s {
+		if i == 0 && !IsNameStartChar(r) || !IsNameChar(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -1,23 +1,75 @@
 package internal
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/converter"
+	"github.com/jdodson3106/goXml2Json/internal/parser"
+)
+
 type ParsedObject interface {
 	Parse(obj string) error
 }
 
+// XmlObject describes the root element of an xml document
 type XmlObject struct {
-	Tag   string
+	// Tag is the name of the root element
+	Tag string
+
+	// Group is not filled by Parse, use ToJson to convert the whole document
 	Group string
+
+	// Value is the text of the root element itself, and Text the text of the root
+	// element and every element inside it, both joined in document order
 	Value string
 	Text  string
-	Id    string
+
+	// Id is the value of the id attribute of the root element, if it has one
+	Id string
 }
 
+// Parse validates obj as xml and populates the XmlObject from its first root element
 func (x *XmlObject) Parse(obj string) error {
+	doc, err := parseXml(obj)
+	if err != nil {
+		return err
+	}
+
+	*x = XmlObject{}
+	for _, el := range doc.Elements {
+		tag, ok := el.(*ast.ElementTagNode)
+		if !ok {
+			continue
+		}
+		x.Tag = tag.Token.Literal
+		if tag.Value.Value != nil {
+			x.Value = fmt.Sprint(tag.Value.Value)
+		}
+		x.Text = textContent(tag)
+		for _, attr := range tag.Attributes {
+			if attr != nil && attr.Key.Value == "id" {
+				x.Id = attr.Value.Value
+			}
+		}
+		break
+	}
 	return nil
 }
 
+// ToJson parses xObject and converts it using the default mapping rules
 func (x *XmlObject) ToJson(xObject string) (*JsonObject, error) {
-	return nil, nil
+	doc, err := parseXml(xObject)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := converter.New(converter.DefaultOptions()).Convert(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &JsonObject{Value: node, dataType: jsonDataType(node)}, nil
 }
 
 type JsonObject struct {
@@ -26,6 +78,73 @@ type JsonObject struct {
 	dataType string
 }
 
+// Parse parses obj as a JSON value and stores it in Value
 func (x *JsonObject) Parse(obj string) error {
+	node, err := parser.Parse(obj, parser.JSON)
+	if err != nil {
+		return err
+	}
+
+	x.Value = node
+	x.dataType = jsonDataType(node.(ast.JsonNode))
 	return nil
 }
+
+// DataType returns the JSON type of Value (object, array, string, number, boolean or null)
+func (x *JsonObject) DataType() string {
+	return x.dataType
+}
+
+// String returns Value encoded as compact JSON
+func (x *JsonObject) String() string {
+	node, ok := x.Value.(ast.JsonNode)
+	if !ok {
+		return ""
+	}
+	return string(converter.Encode(node, ""))
+}
+
+func parseXml(input string) (*ast.Document, error) {
+	node, err := parser.Parse(input, parser.XML)
+	if err != nil {
+		return nil, err
+	}
+	return node.(*ast.Document), nil
+}
+
+// textContent joins the text and CDATA sections of el and of every element inside it in document order
+func textContent(el *ast.ElementTagNode) string {
+	var builder strings.Builder
+	var walk func(el *ast.ElementTagNode)
+	walk = func(el *ast.ElementTagNode) {
+		for _, child := range el.Children {
+			switch n := child.(type) {
+			case *ast.ElementValueNode:
+				builder.WriteString(fmt.Sprint(n.Value))
+			case *ast.CDataNode:
+				builder.WriteString(n.Value)
+			case *ast.ElementTagNode:
+				walk(n)
+			}
+		}
+	}
+	walk(el)
+	return builder.String()
+}
+
+func jsonDataType(node ast.JsonNode) string {
+	switch node.(type) {
+	case *ast.JsonObjectNode:
+		return "object"
+	case *ast.JsonArrayNode:
+		return "array"
+	case *ast.JsonStringNode:
+		return "string"
+	case *ast.JsonNumberNode:
+		return "number"
+	case *ast.JsonBoolNode:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
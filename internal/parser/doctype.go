@@ -0,0 +1,399 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// parseDoctype reads the current DOCTYPE token into doc.Doctype.
+// A DOCTYPE has to come before the root element and can only be given once
+func (p *Parser) parseDoctype(doc *ast.Document) {
+	tok := p.currentToken
+	if doc.Doctype != nil {
+		p.addError(ErrInvalidDeclaration, tok, "the document has more than one DOCTYPE")
+		return
+	}
+	for _, el := range doc.Elements {
+		if _, ok := el.(*ast.ElementTagNode); ok {
+			p.addError(ErrInvalidDeclaration, tok, "the DOCTYPE must come before the root element")
+			return
+		}
+	}
+
+	s := &dtdScanner{input: tok.Literal, start: advance(tok.Pos, "<!DOCTYPE")}
+	doctype, err := s.scanDoctype(tok)
+	if err != nil {
+		p.errors = append(p.errors, &ParseError{
+			Kind:    ErrInvalidDeclaration,
+			Message: err.Error(),
+			Pos:     s.position(),
+			Actual:  tok,
+		})
+		return
+	}
+	doc.Doctype = doctype
+	p.doctype = doctype
+}
+
+/*
+dtdScanner reads the text of a DOCTYPE token. The internal subset is only read as far as needed
+to collect its declarations: the content models of elements are kept as written, and references
+to parameter entities, comments, processing instructions and notations are skipped
+*/
+type dtdScanner struct {
+	input string
+	pos   int
+	start token.Position // position of the first char of input in the document
+}
+
+func (s *dtdScanner) scanDoctype(tok token.Token) (*ast.Doctype, error) {
+	doctype := &ast.Doctype{Token: tok}
+
+	if !s.skipSpace() {
+		return nil, fmt.Errorf("expected whitespace after <!DOCTYPE")
+	}
+	doctype.Name = s.readName()
+	if doctype.Name == "" {
+		return nil, fmt.Errorf("the DOCTYPE has no root element name")
+	}
+
+	s.skipSpace()
+	var err error
+	if doctype.PublicId, doctype.SystemId, err = s.readExternalId(); err != nil {
+		return nil, err
+	}
+
+	s.skipSpace()
+	if s.consume("[") {
+		if err := s.scanSubset(doctype); err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+	}
+
+	if s.pos < len(s.input) {
+		return nil, fmt.Errorf("unexpected %q in the DOCTYPE", s.rest(10))
+	}
+	return doctype, nil
+}
+
+// scanSubset reads the declarations of the internal subset up to and including its closing ']'
+func (s *dtdScanner) scanSubset(doctype *ast.Doctype) error {
+	for {
+		s.skipSpace()
+		switch {
+		case s.consume("]"):
+			return nil
+		case s.pos >= len(s.input):
+			return fmt.Errorf("the internal DTD subset is not closed")
+		case s.consume("<!--"):
+			if !s.skipPast("-->") {
+				return fmt.Errorf("unterminated comment in the internal DTD subset")
+			}
+		case s.consume("<?"):
+			if !s.skipPast("?>") {
+				return fmt.Errorf("unterminated processing instruction in the internal DTD subset")
+			}
+		case s.hasPrefix("<!ENTITY"):
+			entity, err := s.scanEntity()
+			if err != nil {
+				return err
+			}
+			doctype.Entities = append(doctype.Entities, entity)
+		case s.hasPrefix("<!ELEMENT"):
+			element, err := s.scanElement()
+			if err != nil {
+				return err
+			}
+			doctype.Elements = append(doctype.Elements, element)
+		case s.hasPrefix("<!ATTLIST"):
+			attrs, err := s.scanAttlist()
+			if err != nil {
+				return err
+			}
+			doctype.Attributes = append(doctype.Attributes, attrs...)
+		case s.consume("<!NOTATION"):
+			if err := s.skipDeclaration(); err != nil {
+				return err
+			}
+		case s.consume("%"):
+			// a parameter entity reference, which could only be expanded by loading external DTDs
+			if s.readName() == "" || !s.consume(";") {
+				return fmt.Errorf("malformed parameter entity reference in the internal DTD subset")
+			}
+		default:
+			return fmt.Errorf("unexpected %q in the internal DTD subset", s.rest(10))
+		}
+	}
+}
+
+// scanEntity reads <!ENTITY [%] name ("value" | ExternalID [NDATA notation])>
+func (s *dtdScanner) scanEntity() (*ast.EntityDecl, error) {
+	tok := s.token()
+	s.consume("<!ENTITY")
+	s.skipSpace()
+
+	entity := &ast.EntityDecl{}
+	if s.consume("%") {
+		entity.Parameter = true
+		s.skipSpace()
+	}
+	entity.Name = s.readName()
+	if entity.Name == "" {
+		return nil, fmt.Errorf("entity declaration has no name")
+	}
+	tok.Literal = entity.Name
+	entity.Token = tok
+	s.skipSpace()
+
+	if value, ok := s.readQuoted(); ok {
+		entity.Value = value
+	} else {
+		var err error
+		if entity.PublicId, entity.SystemId, err = s.readExternalId(); err != nil {
+			return nil, err
+		}
+		if !entity.IsExternal() {
+			return nil, fmt.Errorf("entity '%s' has neither a value nor a system identifier", entity.Name)
+		}
+		s.skipSpace()
+		if s.consume("NDATA") {
+			s.skipSpace()
+			entity.Notation = s.readName()
+		}
+	}
+
+	return entity, s.endDeclaration("entity '" + entity.Name + "'")
+}
+
+// scanElement reads <!ELEMENT name content>
+func (s *dtdScanner) scanElement() (*ast.ElementDecl, error) {
+	tok := s.token()
+	s.consume("<!ELEMENT")
+	s.skipSpace()
+
+	element := &ast.ElementDecl{Name: s.readName()}
+	if element.Name == "" {
+		return nil, fmt.Errorf("element declaration has no name")
+	}
+	tok.Literal = element.Name
+	element.Token = tok
+
+	end := strings.IndexByte(s.input[s.pos:], '>')
+	if end < 0 {
+		return nil, fmt.Errorf("element declaration '%s' is not closed", element.Name)
+	}
+	element.Content = strings.TrimSpace(s.input[s.pos : s.pos+end])
+	if element.Content == "" {
+		return nil, fmt.Errorf("element declaration '%s' has no content model", element.Name)
+	}
+	s.pos += end + 1
+	return element, nil
+}
+
+// scanAttlist reads <!ATTLIST element (name type default)*>
+func (s *dtdScanner) scanAttlist() ([]*ast.AttributeDecl, error) {
+	s.consume("<!ATTLIST")
+	s.skipSpace()
+
+	element := s.readName()
+	if element == "" {
+		return nil, fmt.Errorf("attribute list declaration has no element name")
+	}
+
+	var attrs []*ast.AttributeDecl
+	for {
+		s.skipSpace()
+		if s.consume(">") {
+			return attrs, nil
+		}
+
+		attr := &ast.AttributeDecl{Token: s.token(), Element: element, Name: s.readName()}
+		if attr.Name == "" {
+			return nil, fmt.Errorf("unexpected %q in the attribute list of '%s'", s.rest(10), element)
+		}
+		attr.Token.Literal = attr.Name
+		s.skipSpace()
+
+		// the type is a name like CDATA or ID, an enumeration like (a|b), or NOTATION (a|b)
+		attr.Type = s.readName()
+		if attr.Type == "" || attr.Type == "NOTATION" {
+			s.skipSpace()
+			end := strings.IndexByte(s.input[s.pos:], ')')
+			if !s.hasPrefix("(") || end < 0 {
+				return nil, fmt.Errorf("attribute '%s' of '%s' has no type", attr.Name, element)
+			}
+			attr.Type = strings.TrimSpace(attr.Type + " " + s.input[s.pos:s.pos+end+1])
+			s.pos += end + 1
+		}
+		s.skipSpace()
+
+		if start := s.pos; s.consume("#") {
+			attr.Mode = "#" + s.readName()
+			switch attr.Mode {
+			case "#REQUIRED", "#IMPLIED":
+				attrs = append(attrs, attr)
+				continue
+			case "#FIXED":
+				s.skipSpace()
+			default:
+				s.pos = start
+				return nil, fmt.Errorf("unknown default %s for attribute '%s' of '%s'", attr.Mode, attr.Name, element)
+			}
+		}
+
+		value, ok := s.readQuoted()
+		if !ok {
+			return nil, fmt.Errorf("attribute '%s' of '%s' has no default", attr.Name, element)
+		}
+		attr.Default = value
+		attrs = append(attrs, attr)
+	}
+}
+
+// readExternalId reads an optional SYSTEM "uri" or PUBLIC "id" "uri"
+func (s *dtdScanner) readExternalId() (publicId, systemId string, err error) {
+	switch {
+	case s.consume("SYSTEM"):
+		s.skipSpace()
+		if systemId, ok := s.readQuoted(); ok {
+			return "", systemId, nil
+		}
+		return "", "", fmt.Errorf("expected a quoted system identifier after SYSTEM")
+	case s.consume("PUBLIC"):
+		s.skipSpace()
+		publicId, ok := s.readQuoted()
+		if !ok {
+			return "", "", fmt.Errorf("expected a quoted public identifier after PUBLIC")
+		}
+		s.skipSpace()
+		if systemId, ok = s.readQuoted(); !ok {
+			return "", "", fmt.Errorf("expected a quoted system identifier after the public identifier")
+		}
+		return publicId, systemId, nil
+	}
+	return "", "", nil
+}
+
+// endDeclaration reads the '>' closing the declaration of what
+func (s *dtdScanner) endDeclaration(what string) error {
+	s.skipSpace()
+	if !s.consume(">") {
+		return fmt.Errorf("unexpected %q in the declaration of %s", s.rest(10), what)
+	}
+	return nil
+}
+
+// skipDeclaration skips to the '>' closing a declaration, ignoring any '>' in quoted strings
+func (s *dtdScanner) skipDeclaration() error {
+	for s.pos < len(s.input) {
+		if _, ok := s.readQuoted(); ok {
+			continue
+		}
+		s.pos++
+		if s.input[s.pos-1] == '>' {
+			return nil
+		}
+	}
+	return fmt.Errorf("declaration in the internal DTD subset is not closed")
+}
+
+// skipSpace skips whitespace and reports whether there was any
+func (s *dtdScanner) skipSpace() bool {
+	start := s.pos
+	for s.pos < len(s.input) && strings.IndexByte(" \t\r\n", s.input[s.pos]) >= 0 {
+		s.pos++
+	}
+	return s.pos > start
+}
+
+// readName reads a name, stopping at whitespace or DTD punctuation
+func (s *dtdScanner) readName() string {
+	start := s.pos
+	for s.pos < len(s.input) && strings.IndexByte(" \t\r\n\"'<>[]()|,%;#", s.input[s.pos]) < 0 {
+		s.pos++
+	}
+	return s.input[start:s.pos]
+}
+
+// readQuoted reads a string wrapped in single or double quotes and returns it without the quotes
+func (s *dtdScanner) readQuoted() (string, bool) {
+	if s.pos >= len(s.input) || (s.input[s.pos] != '"' && s.input[s.pos] != '\'') {
+		return "", false
+	}
+	end := strings.IndexByte(s.input[s.pos+1:], s.input[s.pos])
+	if end < 0 {
+		return "", false
+	}
+	value := s.input[s.pos+1 : s.pos+1+end]
+	s.pos += end + 2
+	return value, true
+}
+
+func (s *dtdScanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(s.input[s.pos:], prefix)
+}
+
+// consume skips over prefix if the input continues with it
+func (s *dtdScanner) consume(prefix string) bool {
+	if !s.hasPrefix(prefix) {
+		return false
+	}
+	s.pos += len(prefix)
+	return true
+}
+
+// skipPast skips to just after the next occurrence of end
+func (s *dtdScanner) skipPast(end string) bool {
+	i := strings.Index(s.input[s.pos:], end)
+	if i < 0 {
+		s.pos = len(s.input)
+		return false
+	}
+	s.pos += i + len(end)
+	return true
+}
+
+// rest returns up to n chars of the remaining input for error messages
+func (s *dtdScanner) rest(n int) string {
+	rest := s.input[s.pos:]
+	if len(rest) > n {
+		rest = rest[:n] + "..."
+	}
+	return rest
+}
+
+// token returns a DOCTYPE token positioned at the current char
+func (s *dtdScanner) token() token.Token {
+	return token.Token{Type: token.DOCTYPE, Pos: s.position()}
+}
+
+// position returns the position of the current char in the document
+func (s *dtdScanner) position() token.Position {
+	return advance(s.start, s.input[:s.pos])
+}
+
+// applyDefaults adds the attributes the doctype declares a default value for and tag does not set,
+// in the order of their declarations. Their tokens point at the declaration in the internal subset
+func (p *Parser) applyDefaults(tag *ast.ElementTagNode) {
+	if p.doctype == nil {
+		return
+	}
+
+	for _, decl := range p.doctype.AttributeDefaults(tag.Token.Literal) {
+		if slices.ContainsFunc(tag.Attributes, func(attr *ast.ElementAttributeNode) bool { return attr.Key.Value == decl.Name }) {
+			continue
+		}
+
+		key := token.Token{Type: token.KEY, Literal: decl.Name, Pos: decl.Token.Pos}
+		value := token.Token{Type: token.VALUE, Literal: normalizeAttribute.Replace(decl.Default), Pos: decl.Token.Pos}
+		tag.Attributes = append(tag.Attributes, &ast.ElementAttributeNode{
+			Key:   &ast.AttributeKeyNode{Token: key, Value: decl.Name},
+			Value: &ast.AttributeValueNode{Token: value, Value: p.decodeText(value)},
+		})
+	}
+}
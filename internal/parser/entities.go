@@ -0,0 +1,187 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// maxExpansion limits the text entity references in a single value can expand to,
+// which stops entities that refer to each other many times over from exhausting memory
+const maxExpansion = 1 << 20
+
+// predefinedEntities the entities every xml document can use without declaring them
+var predefinedEntities = map[string]string{
+	"lt":   "<",
+	"gt":   ">",
+	"amp":  "&",
+	"apos": "'",
+	"quot": `"`,
+}
+
+/*
+decodeText replaces the entity and character references in the literal of tok with the text they
+stand for. Entities are looked up in the predefined entities and then in the internal entities of the
+DOCTYPE, whose values are decoded in turn. The replacement text is never parsed as markup, so an
+entity whose value contains a '<' is an error. Problems are recorded as errors at the reference and
+the reference is kept as written
+*/
+func (p *Parser) decodeText(tok token.Token) string {
+	if !strings.Contains(tok.Literal, "&") {
+		return tok.Literal
+	}
+	p.expansion = 0
+	return p.decodeReferences(tok, tok.Literal, 0, nil)
+}
+
+/*
+decodeReferences decodes text while expanding the entities in expanding. At the top level offset
+is where text starts in the literal of tok. Inside an entity value it is where the outermost
+reference starts, and every problem is reported there
+*/
+func (p *Parser) decodeReferences(tok token.Token, text string, offset int, expanding []string) string {
+	at := func(i int) int {
+		if len(expanding) > 0 {
+			return offset
+		}
+		return offset + i
+	}
+
+	var builder strings.Builder
+	for pos := 0; ; {
+		i := strings.IndexByte(text[pos:], '&')
+		if i < 0 {
+			builder.WriteString(text[pos:])
+			return builder.String()
+		}
+		builder.WriteString(text[pos : pos+i])
+		pos += i
+
+		end := strings.IndexByte(text[pos:], ';')
+		if end <= 1 || strings.ContainsAny(text[pos+1:pos+end], " \t\r\n<&\"'") {
+			p.referenceError(ErrInvalidReference, tok, at(pos), "'&' must be escaped as &amp; or start a reference like &name;")
+			builder.WriteByte('&')
+			pos++
+			continue
+		}
+
+		ref := text[pos : pos+end+1]
+		if value, ok := p.expandReference(tok, ref[1:end], at(pos), expanding); ok {
+			builder.WriteString(value)
+		} else {
+			builder.WriteString(ref)
+		}
+		pos += len(ref)
+	}
+}
+
+// expandReference returns the text the reference &name; at offset in the literal of tok stands for
+func (p *Parser) expandReference(tok token.Token, name string, offset int, expanding []string) (string, bool) {
+	if strings.HasPrefix(name, "#") {
+		r, ok := charReference(name)
+		if !ok {
+			p.referenceError(ErrInvalidReference, tok, offset, fmt.Sprintf("&%s; is not a valid character reference", name))
+			return "", false
+		}
+		return string(r), true
+	}
+
+	if value, ok := predefinedEntities[name]; ok {
+		return value, true
+	}
+
+	var entity *ast.EntityDecl
+	if p.doctype != nil {
+		entity = p.doctype.Entity(name)
+	}
+	switch {
+	case entity == nil:
+		p.referenceError(ErrUndefinedEntity, tok, offset, fmt.Sprintf("undefined entity &%s;", name))
+		return "", false
+	case entity.IsExternal():
+		p.referenceError(ErrUndefinedEntity, tok, offset, fmt.Sprintf("entity &%s; is external and is not loaded", name))
+		return "", false
+	case strings.Contains(entity.Value, "<"):
+		p.referenceError(ErrInvalidReference, tok, offset, fmt.Sprintf("entity &%s; contains markup, which is not expanded", name))
+		return "", false
+	case slices.Contains(expanding, name):
+		p.referenceError(ErrInvalidReference, tok, offset, fmt.Sprintf("entity &%s; refers to itself", name))
+		return "", false
+	case p.expansion < 0:
+		return "", false
+	}
+
+	value := p.decodeReferences(tok, entity.Value, offset, append(expanding, name))
+	if p.expansion >= 0 {
+		p.expansion += len(value)
+	}
+	if p.expansion > maxExpansion {
+		p.referenceError(ErrInvalidReference, tok, offset, fmt.Sprintf("entity &%s; expands to more than %d bytes", name, maxExpansion))
+		p.expansion = -1
+	}
+	if p.expansion < 0 {
+		return "", false
+	}
+	return value, true
+}
+
+// charReference decodes the #123 or #x7B of a character reference into the rune it refers to
+func charReference(name string) (rune, bool) {
+	digits, base := name[1:], 10
+	if strings.HasPrefix(digits, "x") {
+		digits, base = digits[1:], 16
+	}
+	if digits == "" || strings.ContainsAny(digits, "+-") {
+		return 0, false
+	}
+
+	v, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	r := rune(v)
+	return r, isXmlChar(r)
+}
+
+// isXmlChar reports whether r is allowed in an xml document
+func isXmlChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r < 0x20:
+		return false
+	case r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+		return false
+	default:
+		return r <= utf8.MaxRune
+	}
+}
+
+// referenceError records a problem with the reference at offset in the literal of tok
+func (p *Parser) referenceError(kind ErrorKind, tok token.Token, offset int, msg string) {
+	p.errors = append(p.errors, &ParseError{
+		Kind:    kind,
+		Message: msg,
+		Pos:     advance(tok.Pos, tok.Literal[:offset]),
+		Actual:  tok,
+		Path:    copyPath(p.path),
+	})
+}
+
+// advance returns the position reached after reading text from pos
+func advance(pos token.Position, text string) token.Position {
+	pos.Offset += len(text)
+	pos.Char += utf8.RuneCountInString(text)
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		pos.Line += strings.Count(text, "\n")
+		pos.Column = utf8.RuneCountInString(text[i:])
+	} else {
+		pos.Column += utf8.RuneCountInString(text)
+	}
+	return pos
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// ErrorKind classifies a ParseError
+type ErrorKind string
+
+const (
+	// ErrUnexpectedToken a token other than the expected ones was found
+	ErrUnexpectedToken ErrorKind = "unexpected token"
+
+	// ErrUnexpectedEOF the input ended in the middle of a document
+	ErrUnexpectedEOF ErrorKind = "unexpected end of input"
+
+	// ErrIllegalToken the lexer could not make sense of the input
+	ErrIllegalToken ErrorKind = "illegal token"
+
+	// ErrMismatchedTag a closing tag does not match the element it closes
+	ErrMismatchedTag ErrorKind = "mismatched tag"
+
+	// ErrMismatchedQuotes an attribute value is opened and closed with different quotes
+	ErrMismatchedQuotes ErrorKind = "mismatched quotes"
+
+	// ErrInvalidNumber a JSON number can not be represented
+	ErrInvalidNumber ErrorKind = "invalid number"
+
+	// ErrUndefinedEntity a reference names an entity that is not declared, or one that can not be loaded
+	ErrUndefinedEntity ErrorKind = "undefined entity"
+
+	// ErrInvalidReference a '&' does not start a well formed entity or character reference
+	ErrInvalidReference ErrorKind = "invalid reference"
+
+	// ErrInvalidDeclaration the xml declaration, the DOCTYPE or a processing instruction is malformed or misplaced
+	ErrInvalidDeclaration ErrorKind = "invalid declaration"
+
+	// ErrUnboundPrefix the prefix of an element or attribute name is not bound to a namespace
+	ErrUnboundPrefix ErrorKind = "unbound prefix"
+
+	// ErrInvalidNamespace a qualified name or an xmlns declaration is malformed
+	ErrInvalidNamespace ErrorKind = "invalid namespace"
+
+	// ErrDuplicateAttribute an element has two attributes with the same name in the same namespace
+	ErrDuplicateAttribute ErrorKind = "duplicate attribute"
+)
+
+// ParseError describes a single problem found while parsing
+type ParseError struct {
+	Kind    ErrorKind
+	Message string
+
+	// Pos is where the problem was found
+	Pos token.Position
+
+	// Expected lists the token types that would have been accepted, if any
+	Expected []token.TokenType
+
+	// Actual is the token that was found instead
+	Actual token.Token
+
+	// Path holds the names of the elements (or JSON keys and indexes) enclosing
+	// the problem, from the document root down
+	Path []string
+}
+
+func (e *ParseError) Error() string {
+	var builder strings.Builder
+	builder.WriteString(e.Pos.String())
+	builder.WriteString(": ")
+	builder.WriteString(e.Message)
+	if len(e.Path) > 0 {
+		builder.WriteString(" (in ")
+		builder.WriteString(e.PathString())
+		builder.WriteString(")")
+	}
+	return builder.String()
+}
+
+// PathString returns Path joined into a single /root/child/... string
+func (e *ParseError) PathString() string {
+	return "/" + strings.Join(e.Path, "/")
+}
+
+// ErrorList is the error returned for a document with one or more ParseErrors.
+// It unwraps into the individual errors so errors.As can find a *ParseError
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+	}
+}
+
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Err returns the list as an error, or nil when it is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// kindOf picks the ErrorKind for finding actual where something else was expected
+func kindOf(actual token.Token) ErrorKind {
+	switch actual.Type {
+	case token.EOF:
+		return ErrUnexpectedEOF
+	case token.ILLEGAL:
+		return ErrIllegalToken
+	default:
+		return ErrUnexpectedToken
+	}
+}
+
+// copyPath snapshots the current element path for an error
+func copyPath(path []string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return append([]string(nil), path...)
+}
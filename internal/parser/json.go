@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+// JsonParser a recursive descent parser over the token stream of a JSON mode lexer
+type JsonParser struct {
+	l *lexer.Lexer
+
+	currentToken token.Token
+	peekToken    token.Token
+	errors       ErrorList
+	path         []string // keys and array indexes leading to the value being parsed
+}
+
+func NewJson(l *lexer.Lexer) *JsonParser {
+	p := &JsonParser{l: l}
+
+	// queue up the first two tokens into current and peek
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+// Errors returns every error collected while parsing
+func (p *JsonParser) Errors() []*ParseError {
+	return p.errors
+}
+
+// Err returns the collected errors as an ErrorList, or nil if parsing succeeded
+func (p *JsonParser) Err() error {
+	return p.errors.Err()
+}
+
+// ParseJson parses a single JSON value from the input. It returns nil if the value is malformed
+func (p *JsonParser) ParseJson() ast.JsonNode {
+	node := p.parseValue()
+	if node == nil {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.EOF) {
+		p.peekError("end of input", token.EOF)
+		return nil
+	}
+	return node
+}
+
+func (p *JsonParser) nextToken() {
+	p.currentToken = p.peekToken
+	p.peekToken = p.l.NextToken()
+}
+
+func (p *JsonParser) parseValue() ast.JsonNode {
+	switch p.currentToken.Type {
+	case token.OPEN_CURLY:
+		return p.parseObject()
+	case token.OPEN_SQUARE:
+		return p.parseArray()
+	case token.STRING:
+		return &ast.JsonStringNode{Token: p.currentToken, Value: p.currentToken.Literal}
+	case token.INT, token.FLOAT:
+		return p.parseNumber()
+	case token.BOOL:
+		return &ast.JsonBoolNode{Token: p.currentToken, Value: p.currentToken.Literal == "true"}
+	case token.NULL:
+		return &ast.JsonNullNode{Token: p.currentToken}
+	case token.EOF:
+		p.addError(ErrUnexpectedEOF, "unexpected end of input")
+	case token.ILLEGAL:
+		p.addError(ErrIllegalToken, fmt.Sprintf("illegal token %q", p.currentToken.Literal))
+	default:
+		p.addError(ErrUnexpectedToken, fmt.Sprintf("expected a value, got %s %q", p.currentToken.Type, p.currentToken.Literal))
+	}
+	return nil
+}
+
+func (p *JsonParser) parseObject() ast.JsonNode {
+	obj := &ast.JsonObjectNode{Token: p.currentToken}
+
+	// empty object {}
+	if p.expectPeek(token.CLOSE_CURLY) {
+		return obj
+	}
+
+	for {
+		if !p.expectPeek(token.STRING) {
+			p.peekError("a string object key", token.STRING)
+			return nil
+		}
+		key := p.currentToken
+
+		if !p.expectPeek(token.COLON) {
+			p.peekError("':' after object key", token.COLON)
+			return nil
+		}
+
+		p.nextToken()
+		p.path = append(p.path, key.Literal)
+		value := p.parseValue()
+		p.path = p.path[:len(p.path)-1]
+		if value == nil {
+			return nil
+		}
+		obj.Members = append(obj.Members, &ast.JsonMemberNode{Token: key, Key: key.Literal, Value: value})
+
+		if p.expectPeek(token.COMMA) {
+			continue
+		}
+		if !p.expectPeek(token.CLOSE_CURLY) {
+			p.peekError("',' or '}' after object member", token.COMMA, token.CLOSE_CURLY)
+			return nil
+		}
+		return obj
+	}
+}
+
+func (p *JsonParser) parseArray() ast.JsonNode {
+	arr := &ast.JsonArrayNode{Token: p.currentToken}
+
+	// empty array []
+	if p.expectPeek(token.CLOSE_SQUARE) {
+		return arr
+	}
+
+	for {
+		p.nextToken()
+		p.path = append(p.path, strconv.Itoa(len(arr.Elements)))
+		value := p.parseValue()
+		p.path = p.path[:len(p.path)-1]
+		if value == nil {
+			return nil
+		}
+		arr.Elements = append(arr.Elements, value)
+
+		if p.expectPeek(token.COMMA) {
+			continue
+		}
+		if !p.expectPeek(token.CLOSE_SQUARE) {
+			p.peekError("',' or ']' after array element", token.COMMA, token.CLOSE_SQUARE)
+			return nil
+		}
+		return arr
+	}
+}
+
+func (p *JsonParser) parseNumber() ast.JsonNode {
+	num := &ast.JsonNumberNode{Token: p.currentToken}
+
+	// integers that overflow an int64 fall back to a float
+	if p.currentTokenIs(token.INT) {
+		if v, err := strconv.ParseInt(p.currentToken.Literal, 10, 64); err == nil {
+			num.Value = v
+			return num
+		}
+	}
+
+	v, err := strconv.ParseFloat(p.currentToken.Literal, 64)
+	if err != nil {
+		p.addError(ErrInvalidNumber, fmt.Sprintf("invalid number %q", p.currentToken.Literal))
+		return nil
+	}
+	num.Value = v
+	return num
+}
+
+// addError records a ParseError of the given kind found at the current token
+func (p *JsonParser) addError(kind ErrorKind, msg string) {
+	p.errors = append(p.errors, &ParseError{
+		Kind:    kind,
+		Message: msg,
+		Pos:     p.currentToken.Pos,
+		Actual:  p.currentToken,
+		Path:    copyPath(p.path),
+	})
+}
+
+// peekError records that the peek token is not one of the expected token types
+func (p *JsonParser) peekError(description string, expected ...token.TokenType) {
+	var msg string
+	switch p.peekToken.Type {
+	case token.EOF:
+		msg = fmt.Sprintf("expected %s, got end of input", description)
+	case token.ILLEGAL:
+		msg = fmt.Sprintf("expected %s, got illegal token %q", description, p.peekToken.Literal)
+	default:
+		msg = fmt.Sprintf("expected %s, got %s %q", description, p.peekToken.Type, p.peekToken.Literal)
+	}
+
+	p.errors = append(p.errors, &ParseError{
+		Kind:     kindOf(p.peekToken),
+		Message:  msg,
+		Pos:      p.peekToken.Pos,
+		Expected: expected,
+		Actual:   p.peekToken,
+		Path:     copyPath(p.path),
+	})
+}
+
+func (p *JsonParser) currentTokenIs(t token.TokenType) bool {
+	return p.currentToken.Type == t
+}
+
+func (p *JsonParser) peekTokenIs(t token.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+func (p *JsonParser) expectPeek(t token.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	} else {
+		return false
+	}
+}
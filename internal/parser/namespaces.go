@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+)
+
+/*
+bindNamespaces adds the xmlns declarations of tag to the namespaces in scope and resolves the names
+of tag and its attributes against them. Unprefixed elements are in the default namespace while
+unprefixed attributes are in no namespace. The declarations stay in scope until the caller drops
+everything after the length of p.namespaces it had before the call
+*/
+func (p *Parser) bindNamespaces(tag *ast.ElementTagNode) {
+	for _, attr := range tag.Attributes {
+		key := attr.Key
+		key.Name = p.splitName(key.Token)
+
+		var ns ast.Namespace
+		switch {
+		case key.Name.Prefix == "" && key.Name.Local == "xmlns":
+			ns = ast.Namespace{URI: attr.Value.Value}
+		case key.Name.Prefix == "xmlns":
+			ns = ast.Namespace{Prefix: key.Name.Local, URI: attr.Value.Value}
+		default:
+			continue
+		}
+		key.Name.Space = ast.XmlnsNamespace
+
+		if msg := checkNamespace(ns); msg != "" {
+			p.addError(ErrInvalidNamespace, key.Token, msg)
+			continue
+		}
+		tag.Namespaces = append(tag.Namespaces, ns)
+		p.namespaces = append(p.namespaces, ns)
+	}
+
+	tag.Name = p.splitName(tag.Token)
+	tag.Name.Space = p.resolvePrefix(tag.Token, tag.Name)
+
+	seen := make(map[ast.Name]bool, len(tag.Attributes))
+	for _, attr := range tag.Attributes {
+		key := attr.Key
+		if key.Name.Prefix != "" && key.Name.Space == "" {
+			key.Name.Space = p.resolvePrefix(key.Token, key.Name)
+		}
+
+		expanded := ast.Name{Local: key.Name.Local, Space: key.Name.Space}
+		if key.Name.Space == ast.XmlnsNamespace {
+			// xmlns and xmlns:xmlns would otherwise clash as both have the local name xmlns
+			expanded.Prefix = key.Name.Prefix
+		}
+		if seen[expanded] {
+			p.addError(ErrDuplicateAttribute, key.Token, fmt.Sprintf("attribute '%s' is given more than once", key.Value))
+		}
+		seen[expanded] = true
+	}
+}
+
+// splitName splits the qualified name in the literal of tok, reporting names like a:b:c or a: that can not be split
+func (p *Parser) splitName(tok token.Token) ast.Name {
+	name := ast.SplitName(tok.Literal)
+	if strings.Contains(tok.Literal, ":") && (name.Prefix == "" || name.Local == "" || strings.Contains(name.Local, ":")) {
+		p.addError(ErrInvalidNamespace, tok, fmt.Sprintf("'%s' is not a valid qualified name", tok.Literal))
+		return ast.Name{Local: tok.Literal}
+	}
+	return name
+}
+
+// resolvePrefix returns the namespace the prefix of name is bound to, reporting an unbound prefix at tok
+func (p *Parser) resolvePrefix(tok token.Token, name ast.Name) string {
+	if name.Prefix == "xml" {
+		return ast.XmlNamespace
+	}
+	for i := len(p.namespaces) - 1; i >= 0; i-- {
+		if p.namespaces[i].Prefix == name.Prefix {
+			return p.namespaces[i].URI
+		}
+	}
+	if name.Prefix != "" {
+		p.addError(ErrUnboundPrefix, tok, fmt.Sprintf("namespace prefix '%s' of '%s' is not bound", name.Prefix, tok.Literal))
+	}
+	return ""
+}
+
+// checkNamespace returns why the declaration ns is not allowed, or "" when it is
+func checkNamespace(ns ast.Namespace) string {
+	switch {
+	case ns.Prefix == "xmlns":
+		return "the xmlns prefix can not be declared"
+	case ns.Prefix == "xml" && ns.URI != ast.XmlNamespace:
+		return fmt.Sprintf("the xml prefix can only be bound to %s", ast.XmlNamespace)
+	case ns.Prefix != "xml" && ns.URI == ast.XmlNamespace:
+		return fmt.Sprintf("only the xml prefix can be bound to %s", ast.XmlNamespace)
+	case ns.URI == ast.XmlnsNamespace:
+		return fmt.Sprintf("no prefix can be bound to %s", ast.XmlnsNamespace)
+	case ns.Prefix != "" && ns.URI == "":
+		return fmt.Sprintf("namespace prefix '%s' can not be undeclared", ns.Prefix)
+	}
+	return ""
+}
@@ -2,6 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/jdodson3106/goXml2Json/internal/ast"
 	"github.com/jdodson3106/goXml2Json/internal/lexer"
 	"github.com/jdodson3106/goXml2Json/internal/token"
@@ -12,16 +15,68 @@ const (
 	XML  = "xml"
 )
 
+// Options controls how a Parser builds the document
+type Options struct {
+	// Whitespace selects what happens to the whitespace in element text. The zero value is WhitespaceTrim
+	Whitespace WhitespaceMode
+}
+
 type Parser struct {
-	l *lexer.Lexer
+	l    *lexer.Lexer
+	opts Options
 
 	currentToken token.Token
 	peekToken    token.Token
-	errors       []string
+	errors       ErrorList
+	path         []string        // names of the elements currently being parsed
+	pendingClose *token.Token    // a closing tag that ended a child early and still has to close an ancestor
+	doctype      *ast.Doctype    // declares the entities references are decoded with
+	namespaces   []ast.Namespace // xmlns declarations in scope, innermost last
+	whitespace   WhitespaceMode  // the whitespace mode of the element being parsed
+	expansion    int             // bytes expanded from entities for the current value, or -1 once it is over maxExpansion
+}
+
+// Parse lexes and parses input as a JSON or XML document depending on docType.
+// XML input is first decoded according to its declared encoding (see lexer.Decode)
+// and produces an *ast.Document. JSON input produces an ast.JsonNode
+func Parse(input, docType string) (ast.Node, error) {
+	if docType == XML {
+		decoded, err := lexer.Decode([]byte(input))
+		if err != nil {
+			return nil, err
+		}
+		input = decoded
+	}
+
+	l, err := lexer.New(input, docType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch docType {
+	case JSON:
+		p := NewJson(l)
+		node := p.ParseJson()
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		p := New(l)
+		doc := p.ParseDocument()
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l}
+	return NewWithOptions(l, Options{})
+}
+
+func NewWithOptions(l *lexer.Lexer, opts Options) *Parser {
+	p := &Parser{l: l, opts: opts, whitespace: opts.Whitespace}
 
 	// queue up the first two tokens into current and peek
 	p.nextToken()
@@ -30,14 +85,55 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// Errors returns every error collected while parsing
+func (p *Parser) Errors() []*ParseError {
+	return p.errors
+}
+
+// Err returns the collected errors as an ErrorList, or nil if parsing succeeded
+func (p *Parser) Err() error {
+	return p.errors.Err()
+}
+
+// ParseDocument parses every root element of the input. Malformed markup does not stop parsing:
+// the problem is recorded in Errors and the parser resyncs at the next tag boundary, so the
+// returned document holds everything that could be recovered
 func (p *Parser) ParseDocument() *ast.Document {
 	doc := &ast.Document{}
 	doc.Elements = []ast.ElementNode{}
 
 	for p.currentToken.Type != token.EOF {
-		el := p.parseElement()
-		if el != nil {
-			doc.Elements = append(doc.Elements, el)
+		switch {
+		case p.currTokenIs(token.OPEN_ANGLE) && p.peekTokenIs(token.TAG):
+			p.nextToken()
+			el := p.parseElement()
+			if el != nil {
+				doc.Elements = append(doc.Elements, el)
+			}
+		case p.currTokenIs(token.OPEN_ANGLE) && p.peekTokenIs(token.XML_TERMINATOR):
+			p.nextToken()
+			p.parseStrayClosingTag()
+		case p.currTokenIs(token.VALUE) && isWhitespace(p.currentToken.Literal):
+			// whitespace around the root element is never part of the content
+		case p.currTokenIs(token.COMMENT):
+			doc.Elements = append(doc.Elements, p.parseComment())
+		case p.currTokenIs(token.DOCTYPE):
+			p.parseDoctype(doc)
+		case p.currTokenIs(token.PI):
+			pi := p.parseProcInst()
+			switch {
+			case pi == nil:
+			case pi.Target != "xml":
+				doc.Elements = append(doc.Elements, pi)
+			case pi.Token.Pos.Offset != 0:
+				p.misplacedDeclaration(pi)
+			default:
+				doc.Declaration = p.parseDeclaration(pi)
+			}
+		default:
+			p.addError(kindOf(p.currentToken), p.currentToken,
+				fmt.Sprintf("unexpected %s %q outside of an element", p.currentToken.Type, p.currentToken.Literal), token.OPEN_ANGLE)
+			p.skipUntilPeek(token.OPEN_ANGLE)
 		}
 		p.nextToken()
 	}
@@ -62,69 +158,279 @@ func (p *Parser) parseElement() ast.ElementNode {
 func (p *Parser) parseTagStatement() ast.ElementNode {
 	tag := &ast.ElementTagNode{Token: p.currentToken}
 
-	// parse all attributes from statement
+	p.path = append(p.path, tag.Token.Literal)
+	defer func() { p.path = p.path[:len(p.path)-1] }()
+
+	// parse all attributes from statement. a malformed attribute is dropped and
+	// parsing resumes at the end of the start tag
 	for p.expectPeek(token.KEY) {
-		tag.Attributes = append(tag.Attributes, p.parseAttribute())
+		attr := p.parseAttribute()
+		if attr == nil {
+			p.skipUntilPeek(token.CLOSE_ANGLE, token.XML_TERMINATOR, token.OPEN_ANGLE)
+			continue
+		}
+		tag.Attributes = append(tag.Attributes, attr)
 	}
+	p.applyDefaults(tag)
+
+	scope, whitespace := len(p.namespaces), p.whitespace
+	p.bindNamespaces(tag)
+	p.whitespace = p.spaceMode(tag)
+	defer func() { p.namespaces, p.whitespace = p.namespaces[:scope], whitespace }()
+
+	// whitespace is handled and the value joined once the content is read, however the element ends
+	defer func() {
+		p.spaceContent(tag)
+		p.joinText(tag)
+	}()
 
 	// this means there is no value, so the tag has an early termination like <tag />
 	if p.expectPeek(token.XML_TERMINATOR) {
 		// validate the last token is the '>' char
 		if !p.expectPeek(token.CLOSE_ANGLE) {
-			p.errors = append(p.errors, "missing closing angle at element tag termination")
-			return nil
+			p.peekError("missing closing angle at element tag termination", token.CLOSE_ANGLE)
+			p.skipUntilPeek(token.CLOSE_ANGLE, token.OPEN_ANGLE)
+			p.expectPeek(token.CLOSE_ANGLE)
 		}
 		tag.EndToken = p.currentToken
 		return tag
 	}
 
-	// if the next tag after the attrs is not a close angle then fail
+	// if the next tag after the attrs is not a close angle then skip to the end of the start tag
 	if !p.expectPeek(token.CLOSE_ANGLE) {
-		p.errors = append(p.errors, "expected closing angle tag for tag")
-		return nil
+		p.peekError("expected closing angle tag for tag", token.CLOSE_ANGLE)
+		p.skipUntilPeek(token.CLOSE_ANGLE, token.OPEN_ANGLE)
+		p.expectPeek(token.CLOSE_ANGLE)
 	}
 
-	// if the next token is not a value then it must have children or nothing
-	// this is the recursive bit of the recursive descent parser
-	if !p.expectPeek(token.VALUE) && p.expectPeek(token.OPEN_ANGLE) {
-		// the token is an empty element (<tag></tag>)
-		if p.expectPeek(token.XML_TERMINATOR) {
+	// read the element content until its closing tag. every child element is parsed
+	// recursively and collected in document order
+	for {
+		// a child was implicitly closed by a closing tag meant for this element or one of its ancestors
+		if p.pendingClose != nil {
+			if p.pendingClose.Literal != tag.Token.Literal {
+				return tag
+			}
+			closing := *p.pendingClose
+			p.pendingClose = nil
+			p.finishClosingTag(tag, closing)
+			return tag
+		}
+
+		switch {
+		case p.expectPeek(token.VALUE):
+			tag.Children = append(tag.Children, &ast.ElementValueNode{Token: p.currentToken, Value: p.decodeText(p.currentToken)})
+		case p.expectPeek(token.CDATA):
+			tag.Children = append(tag.Children, &ast.CDataNode{Token: p.currentToken, Value: p.currentToken.Literal})
+		case p.expectPeek(token.COMMENT):
+			tag.Children = append(tag.Children, p.parseComment())
+		case p.expectPeek(token.PI):
+			pi := p.parseProcInst()
+			switch {
+			case pi == nil:
+			case pi.Target == "xml":
+				p.misplacedDeclaration(pi)
+			default:
+				tag.Children = append(tag.Children, pi)
+			}
+		case p.expectPeek(token.OPEN_ANGLE):
+			if p.expectPeek(token.XML_TERMINATOR) {
+				if p.parseClosingTag(tag) {
+					return tag
+				}
+				continue
+			}
+
 			if !p.expectPeek(token.TAG) {
-				p.errors = append(p.errors, "missing tag at element tag termination")
-				return nil
+				p.peekError("missing tag name for child element", token.TAG, token.XML_TERMINATOR)
+				p.skipUntilPeek(token.OPEN_ANGLE)
+				continue
 			}
-			tag.EndToken = p.currentToken
+
+			child := p.parseTagStatement()
+			tag.Elements = append(tag.Elements, child.(*ast.ElementTagNode))
+			tag.Children = append(tag.Children, child)
+		case p.peekTokenIs(token.EOF):
+			p.peekError(fmt.Sprintf("element '%s' is not closed", tag.Token.Literal), token.VALUE, token.OPEN_ANGLE)
 			return tag
+		default:
+			// the next token should be the opening of a child or the closing tag
+			p.peekError("Invalid xml syntax. Expected open angle for element tag", token.VALUE, token.OPEN_ANGLE)
+			p.nextToken()
+			p.skipUntilPeek(token.OPEN_ANGLE)
+		}
+	}
+}
+
+// normalizeAttribute replaces the line breaks and tabs in an attribute value with spaces
+var normalizeAttribute = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ", "\t", " ")
+
+func (p *Parser) parseComment() *ast.CommentNode {
+	return &ast.CommentNode{Token: p.currentToken, Value: p.currentToken.Literal}
+}
+
+// parseProcInst splits the current PI token into its target and data.
+// It returns nil when the instruction does not have a valid target
+func (p *Parser) parseProcInst() *ast.ProcInstNode {
+	pi := &ast.ProcInstNode{Token: p.currentToken, Target: p.currentToken.Literal}
+	if i := strings.IndexAny(pi.Target, " \t\r\n"); i >= 0 {
+		pi.Target, pi.Data = pi.Target[:i], strings.TrimLeft(pi.Target[i:], " \t\r\n")
+	}
+
+	switch {
+	case pi.Target == "":
+		p.addError(ErrInvalidDeclaration, pi.Token, "processing instruction has no target")
+		return nil
+	case pi.Target != "xml" && strings.EqualFold(pi.Target, "xml"):
+		p.addError(ErrInvalidDeclaration, pi.Token, fmt.Sprintf("processing instruction target '%s' is reserved", pi.Target))
+		return nil
+	}
+	return pi
+}
+
+func (p *Parser) misplacedDeclaration(pi *ast.ProcInstNode) {
+	p.addError(ErrInvalidDeclaration, pi.Token, "the xml declaration is only allowed at the very start of the document")
+}
+
+/*
+parseDeclaration reads the pseudo attributes of the <?xml ...?> declaration in pi.
+The version is required and has to come first, followed by the optional encoding and standalone.
+It returns nil if the declaration is malformed
+*/
+func (p *Parser) parseDeclaration(pi *ast.ProcInstNode) *ast.Declaration {
+	decl := &ast.Declaration{Token: pi.Token}
+	names := []string{"version", "encoding", "standalone"}
+
+	next := 0
+	for rest := pi.Data; strings.TrimSpace(rest) != ""; {
+		name, value, remaining, ok := pseudoAttribute(rest)
+		if !ok {
+			p.addError(ErrInvalidDeclaration, pi.Token, fmt.Sprintf("malformed xml declaration '%s'", pi.Data))
+			return nil
 		}
 
-		child := p.parseTagStatement()
-		if child != nil {
-			tag.Elements = append(tag.Elements, &child)
-		} else {
-			p.errors = append(p.errors, "error parsing child element")
+		i := slices.Index(names[next:], name)
+		if i < 0 {
+			p.addError(ErrInvalidDeclaration, pi.Token, fmt.Sprintf("unexpected '%s' in the xml declaration", name))
 			return nil
 		}
-	}
+		next += i + 1
 
-	if p.currTokenIs(token.VALUE) {
-		tag.Value = ast.ElementValueNode{
-			Token: p.currentToken,
-			Value: p.currentToken.Literal,
+		switch name {
+		case "version":
+			decl.Version = value
+		case "encoding":
+			decl.Encoding = value
+		case "standalone":
+			decl.Standalone = value
 		}
+		rest = remaining
 	}
 
-	// the next token should be the opening of the next tag
-	if !p.expectPeek(token.OPEN_ANGLE) {
-		p.errors = append(p.errors, "Invalid xml syntax. Expected open angle for element tag")
+	switch {
+	case decl.Version == "":
+		p.addError(ErrInvalidDeclaration, pi.Token, "the xml declaration is missing its version")
+		return nil
+	case !isXmlVersion(decl.Version):
+		p.addError(ErrInvalidDeclaration, pi.Token, fmt.Sprintf("unsupported xml version '%s'", decl.Version))
+		return nil
+	case decl.Standalone != "" && decl.Standalone != "yes" && decl.Standalone != "no":
+		p.addError(ErrInvalidDeclaration, pi.Token, fmt.Sprintf("standalone must be 'yes' or 'no', got '%s'", decl.Standalone))
 		return nil
 	}
+	return decl
+}
 
-	if !p.expectPeek(token.XML_TERMINATOR) || !p.expectPeek(token.TAG) {
-		p.errors = append(p.errors, "no closing tag for element.")
-		return nil
+// pseudoAttribute reads the first name="value" pair of s and returns what follows it
+func pseudoAttribute(s string) (name, value, rest string, ok bool) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	name, s, ok = strings.Cut(s, "=")
+	if !ok {
+		return "", "", "", false
+	}
+	name = strings.TrimRight(name, " \t\r\n")
+
+	s = strings.TrimLeft(s, " \t\r\n")
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return "", "", "", false
+	}
+	value, rest, ok = strings.Cut(s[1:], s[:1])
+	return name, value, rest, ok
+}
+
+// isXmlVersion reports whether version has the 1.x form of every xml 1 version
+func isXmlVersion(version string) bool {
+	digits, ok := strings.CutPrefix(version, "1.")
+	if !ok || digits == "" {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+parseClosingTag reads the '</tag>' that closes tag. The current token is expected to
+be the '/' of the closing tag. It reports whether tag is now closed, which is also the case
+when the closing tag belongs to one of its ancestors. That closing tag is then left in
+pendingClose for the ancestor to pick up
+*/
+func (p *Parser) parseClosingTag(tag *ast.ElementTagNode) bool {
+	if !p.expectPeek(token.TAG) {
+		p.peekError("no closing tag for element.", token.TAG)
+		p.skipUntilPeek(token.CLOSE_ANGLE, token.OPEN_ANGLE)
+		p.expectPeek(token.CLOSE_ANGLE)
+		return true
+	}
+
+	if p.currentToken.Literal == tag.Token.Literal {
+		p.finishClosingTag(tag, p.currentToken)
+		return true
+	}
+
+	p.addError(ErrMismatchedTag, p.currentToken, fmt.Sprintf("Mismatching closing tag '%s' for element '%s'", p.currentToken.Literal, tag.Token.Literal), token.TAG)
+	for _, name := range p.path[:len(p.path)-1] {
+		if name == p.currentToken.Literal {
+			closing := p.currentToken
+			p.pendingClose = &closing
+			return true
+		}
+	}
+
+	// a stray closing tag that matches no open element is skipped
+	p.expectPeek(token.CLOSE_ANGLE)
+	return false
+}
+
+// finishClosingTag records closing as the end of tag and reads the final '>'
+func (p *Parser) finishClosingTag(tag *ast.ElementTagNode, closing token.Token) {
+	tag.EndToken = closing
+	if !p.expectPeek(token.CLOSE_ANGLE) {
+		p.peekError("missing closing angle at element tag termination", token.CLOSE_ANGLE)
+		p.skipUntilPeek(token.CLOSE_ANGLE, token.OPEN_ANGLE)
+		p.expectPeek(token.CLOSE_ANGLE)
+	}
+}
+
+// parseStrayClosingTag reports and skips a closing tag found outside of any element
+func (p *Parser) parseStrayClosingTag() {
+	if p.expectPeek(token.TAG) {
+		p.addError(ErrMismatchedTag, p.currentToken, fmt.Sprintf("closing tag '%s' has no matching element", p.currentToken.Literal))
+	} else {
+		p.peekError("no closing tag for element.", token.TAG)
+	}
+	p.skipUntilPeek(token.CLOSE_ANGLE, token.OPEN_ANGLE)
+	p.expectPeek(token.CLOSE_ANGLE)
+}
+
+// skipUntilPeek advances until the peek token is one of types or the end of the input
+func (p *Parser) skipUntilPeek(types ...token.TokenType) {
+	for !p.peekTokenIs(token.EOF) && !slices.Contains(types, p.peekToken.Type) {
+		p.nextToken()
 	}
-	tag.EndToken = p.currentToken
-	return tag
 }
 
 func (p *Parser) parseAttribute() *ast.ElementAttributeNode {
@@ -133,39 +439,58 @@ func (p *Parser) parseAttribute() *ast.ElementAttributeNode {
 	// set the attribute key and makes sure the next token is an equal sign
 	key := &ast.AttributeKeyNode{Token: p.currentToken, Value: p.currentToken.Literal}
 	if !p.expectPeek(token.EQUAL) {
-		p.errors = append(p.errors, fmt.Sprintf("Expected '=', got %v", p.currentToken.Type))
+		p.peekError(fmt.Sprintf("Expected '=', got %v", p.peekToken.Type), token.EQUAL)
 		return nil
 	}
 
 	// make sure the next token in an opening single or double quote to hold the value
 	if !p.expectPeek(token.QUOTE) && !p.expectPeek(token.SINGLE_QUOTE) {
-		p.errors = append(p.errors, "XML element attribute values must be wrapped in quotes.")
+		p.peekError("XML element attribute values must be wrapped in quotes.", token.QUOTE, token.SINGLE_QUOTE)
 		return nil
 	}
 	quoteType = p.currentToken.Type // store the opening quote so we can make sure we have a valid match
 
 	// confirm the next token is actually a value and use to construct the attribute value node
 	if !p.expectPeek(token.VALUE) {
-		p.errors = append(p.errors, fmt.Sprintf("Expected token.VALUE, got %v", p.currentToken.Type))
+		p.peekError(fmt.Sprintf("Expected token.VALUE, got %v", p.peekToken.Type), token.VALUE)
 		return nil
 	}
-	val := &ast.AttributeValueNode{Token: p.currentToken, Value: p.currentToken.Literal}
+	tok := p.currentToken
+	tok.Literal = normalizeAttribute.Replace(tok.Literal)
+	val := &ast.AttributeValueNode{Token: tok, Value: p.decodeText(tok)}
 
 	// make sure there is a closing quote
 	if !p.expectPeek(token.QUOTE) && !p.expectPeek(token.SINGLE_QUOTE) {
-		p.errors = append(p.errors, "XML element attribute missing closing quote.")
+		p.peekError("XML element attribute missing closing quote.", token.QUOTE, token.SINGLE_QUOTE)
 		return nil
 	}
 
 	// assert the closing and opening quotes match
 	if p.currentToken.Type != quoteType {
-		p.errors = append(p.errors, fmt.Sprintf("Mismatching quotes for value '%s'", val.Value))
+		p.addError(ErrMismatchedQuotes, p.currentToken, fmt.Sprintf("Mismatching quotes for value '%s'", val.Value), quoteType)
 		return nil
 	}
 
 	return &ast.ElementAttributeNode{Key: key, Value: val}
 }
 
+// addError records a ParseError of the given kind found at tok
+func (p *Parser) addError(kind ErrorKind, tok token.Token, msg string, expected ...token.TokenType) {
+	p.errors = append(p.errors, &ParseError{
+		Kind:     kind,
+		Message:  msg,
+		Pos:      tok.Pos,
+		Expected: expected,
+		Actual:   tok,
+		Path:     copyPath(p.path),
+	})
+}
+
+// peekError records that the peek token is not one of the expected token types
+func (p *Parser) peekError(msg string, expected ...token.TokenType) {
+	p.addError(kindOf(p.peekToken), p.peekToken, msg, expected...)
+}
+
 func (p *Parser) currTokenIs(t token.TokenType) bool {
 	return p.currentToken.Type == t
 }
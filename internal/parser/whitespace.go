@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+)
+
+// WhitespaceMode selects what happens to the whitespace in the text of elements
+type WhitespaceMode int
+
+const (
+	// WhitespaceTrim drops the whitespace at the start and end of the content of an element, and text
+	// segments that are only whitespace like the indentation between child elements. The whitespace
+	// between text and inline elements like the spaces in <p>Hello <b>world</b> again</p> is kept
+	WhitespaceTrim WhitespaceMode = iota
+
+	// WhitespaceCollapse trims like WhitespaceTrim and also turns every run of whitespace inside
+	// a text segment into a single space
+	WhitespaceCollapse
+
+	// WhitespacePreserve keeps every text segment exactly as written, indentation included
+	WhitespacePreserve
+)
+
+// ParseWhitespaceMode returns the mode named trim, collapse or preserve
+func ParseWhitespaceMode(name string) (WhitespaceMode, bool) {
+	switch name {
+	case "trim":
+		return WhitespaceTrim, true
+	case "collapse":
+		return WhitespaceCollapse, true
+	case "preserve":
+		return WhitespacePreserve, true
+	default:
+		return 0, false
+	}
+}
+
+// spaceMode returns the whitespace mode for the content of tag. xml:space="preserve" preserves the
+// whitespace of the element and everything inside it, and xml:space="default" returns to the mode
+// the parser was created with
+func (p *Parser) spaceMode(tag *ast.ElementTagNode) WhitespaceMode {
+	for _, attr := range tag.Attributes {
+		if attr.Key.Name.Space != ast.XmlNamespace || attr.Key.Name.Local != "space" {
+			continue
+		}
+		switch attr.Value.Value {
+		case "preserve":
+			return WhitespacePreserve
+		case "default":
+			return p.opts.Whitespace
+		}
+	}
+	return p.whitespace
+}
+
+// spaceContent applies the current whitespace mode to the text segments of tag once its content is read.
+// Segments that are only whitespace are dropped when the content has no other text, like the indentation
+// between child elements, or when they are at its start or end. Any other segment is kept, so the space in
+// <p>Read <b>this</b> <i>now</i>.</p> stays. Trimming moves the token of a segment to its first kept char
+func (p *Parser) spaceContent(tag *ast.ElementTagNode) {
+	if p.whitespace == WhitespacePreserve {
+		return
+	}
+
+	if !hasText(tag.Children) {
+		tag.Children = slices.DeleteFunc(tag.Children, func(child ast.ElementNode) bool {
+			_, ok := child.(*ast.ElementValueNode)
+			return ok
+		})
+		return
+	}
+
+	for _, text := range edgeText(tag.Children, 0, 1) {
+		literal := strings.TrimLeft(text.Token.Literal, " \t\r\n")
+		lead := text.Token.Literal[:len(text.Token.Literal)-len(literal)]
+		text.Token.Pos = advance(text.Token.Pos, lead)
+		text.Token.Literal = literal
+		text.Value = strings.TrimPrefix(text.Value.(string), lead)
+	}
+	for _, text := range edgeText(tag.Children, len(tag.Children)-1, -1) {
+		literal := strings.TrimRight(text.Token.Literal, " \t\r\n")
+		text.Value = strings.TrimSuffix(text.Value.(string), text.Token.Literal[len(literal):])
+		text.Token.Literal = literal
+	}
+	tag.Children = slices.DeleteFunc(tag.Children, func(child ast.ElementNode) bool {
+		text, ok := child.(*ast.ElementValueNode)
+		return ok && text.Token.Literal == ""
+	})
+
+	if p.whitespace != WhitespaceCollapse {
+		return
+	}
+	for _, child := range tag.Children {
+		if text, ok := child.(*ast.ElementValueNode); ok {
+			text.Value = collapse(text.Value.(string))
+		}
+	}
+}
+
+// hasText reports whether children hold a CDATA section or a text segment that is not only whitespace
+func hasText(children []ast.ElementNode) bool {
+	for _, child := range children {
+		switch n := child.(type) {
+		case *ast.CDataNode:
+			return true
+		case *ast.ElementValueNode:
+			if !isWhitespace(n.Token.Literal) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// edgeText returns the text segments at the start or end of children, walking from i by step past
+// comments, processing instructions and segments that are only whitespace. The last segment returned
+// is the first one with other chars, or none when the content starts or ends with anything else
+func edgeText(children []ast.ElementNode, i, step int) []*ast.ElementValueNode {
+	var texts []*ast.ElementValueNode
+	for ; i >= 0 && i < len(children); i += step {
+		switch n := children[i].(type) {
+		case *ast.CommentNode, *ast.ProcInstNode:
+			continue
+		case *ast.ElementValueNode:
+			texts = append(texts, n)
+			if !isWhitespace(n.Token.Literal) {
+				return texts
+			}
+		default:
+			return texts
+		}
+	}
+	return texts
+}
+
+// joinText joins the text of tag into its Value. Outside of preserve mode the whitespace on both sides
+// of a child element is merged, so that leaving the element out of the text does not turn the single
+// space around it into two
+func (p *Parser) joinText(tag *ast.ElementTagNode) {
+	tag.JoinText()
+	if p.whitespace == WhitespacePreserve || !tag.HasMixedContent() {
+		return
+	}
+
+	var builder strings.Builder
+	seam := false
+	for _, child := range tag.Children {
+		switch n := child.(type) {
+		case *ast.ElementTagNode:
+			seam = true
+		case *ast.CDataNode:
+			builder.WriteString(n.Value)
+			seam = false
+		case *ast.ElementValueNode:
+			text := n.Value.(string)
+			if seam && endsWithWhitespace(builder.String()) {
+				text = strings.TrimLeft(text, " \t\r\n")
+			}
+			builder.WriteString(text)
+			seam = false
+		}
+	}
+	tag.Value.Value = builder.String()
+}
+
+// collapse replaces every run of whitespace in text with a single space
+func collapse(text string) string {
+	var builder strings.Builder
+	space := false
+	for _, r := range text {
+		if strings.ContainsRune(" \t\r\n", r) {
+			space = true
+			continue
+		}
+		if space {
+			builder.WriteByte(' ')
+			space = false
+		}
+		builder.WriteRune(r)
+	}
+	if space {
+		builder.WriteByte(' ')
+	}
+	return builder.String()
+}
+
+// isWhitespace reports whether text only holds the whitespace chars of xml
+func isWhitespace(text string) bool {
+	return strings.TrimLeft(text, " \t\r\n") == ""
+}
+
+// endsWithWhitespace reports whether text ends with one of the whitespace chars of xml
+func endsWithWhitespace(text string) bool {
+	return text != "" && strings.ContainsRune(" \t\r\n", rune(text[len(text)-1]))
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal/cli"
+	"github.com/stretchr/testify/require"
+)
+
+// runCli runs the xml2json command with stdin as input and returns its exit code, stdout and stderr
+func runCli(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCliConvertsStdin(t *testing.T) {
+	code, stdout, stderr := runCli(`<name category="given">Justin</name>`, "--compact")
+	require.Equal(t, 0, code)
+	require.Equal(t, `{"name":{"@category":"given","#text":"Justin"}}`+"\n", stdout)
+	require.Empty(t, stderr)
+
+	code, stdout, _ = runCli(`<a><b>1</b></a>`)
+	require.Equal(t, 0, code)
+	require.Equal(t, "{\n  \"a\": {\n    \"b\": \"1\"\n  }\n}\n", stdout)
+}
+
+func TestCliConvertsFile(t *testing.T) {
+	code, stdout, stderr := runCli("", "--compact", filepath.Join(testFilesDir, "nestedElementsTest.xml"))
+	require.Equal(t, 0, code)
+	require.Equal(t, `{"employee":{"@role":"programmer","name":"Justin","dob":"09-27-1989","phone":{"@type":"mobile","#text":"8675301"}}}`+"\n", stdout)
+	require.Empty(t, stderr)
+
+	code, stdout, stderr = runCli("", filepath.Join(t.TempDir(), "missing.xml"))
+	require.Equal(t, 1, code)
+	require.Empty(t, stdout)
+	require.Contains(t, stderr, "missing.xml: no such file or directory")
+}
+
+func TestCliWritesOutputFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.json")
+
+	code, stdout, stderr := runCli(`<a>1</a>`, "--compact", "-o", out)
+	require.Equal(t, 0, code)
+	require.Empty(t, stdout)
+	require.Empty(t, stderr)
+
+	written, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":"1"}`+"\n", string(written))
+}
+
+func TestCliReportsParseErrors(t *testing.T) {
+	input := "<a>\n  <b>1</c>\n</a>"
+
+	code, stdout, stderr := runCli(input, "--compact")
+	require.Equal(t, 1, code)
+	require.Empty(t, stdout)
+	require.Equal(t,
+		"xml2json: stdin is not valid xml:\n"+
+			"  2:9: Mismatching closing tag 'c' for element 'b' (in /a/b)\n"+
+			"  3:3: Mismatching closing tag 'a' for element 'b' (in /a/b)\n",
+		stderr,
+	)
+
+	// --partial still writes what was recovered, but keeps the failing exit code
+	code, stdout, stderr = runCli(input, "--compact", "--partial")
+	require.Equal(t, 1, code)
+	require.Equal(t, `{"a":{"b":"1"}}`+"\n", stdout)
+	require.Contains(t, stderr, "Mismatching closing tag 'c'")
+}
+
+func TestCliReverse(t *testing.T) {
+	code, stdout, stderr := runCli(`{"a": {"@id": 1, "b": ["x", "y"]}}`, "--reverse", "--compact")
+	require.Equal(t, 0, code)
+	require.Equal(t, `<a id="1"><b>x</b><b>y</b></a>`+"\n", stdout)
+	require.Empty(t, stderr)
+
+	code, stdout, stderr = runCli(`{"a": }`, "--reverse")
+	require.Equal(t, 1, code)
+	require.Empty(t, stdout)
+	require.True(t, strings.HasPrefix(stderr, "xml2json: stdin is not valid JSON:\n  1:7: "), stderr)
+
+	code, _, stderr = runCli(`{"a": {"x:b": 1}}`, "--reverse")
+	require.Equal(t, 1, code)
+	require.Equal(t, "xml2json: prefix \"x\" of element x:b is not bound by an xmlns:x attribute\n", stderr)
+}
+
+func TestCliJsonMLKeepsText(t *testing.T) {
+	input := "<p>Hello <b>world</b> again</p>"
+
+	code, stdout, _ := runCli(input, "--convention", "jsonml", "--compact")
+	require.Equal(t, 0, code)
+	require.Equal(t, `["p","Hello ",["b","world"]," again"]`+"\n", stdout)
+
+	code, stdout, _ = runCli(stdout, "--convention", "jsonml", "--reverse", "--compact")
+	require.Equal(t, 0, code)
+	require.Equal(t, input+"\n", stdout)
+
+	// an explicit --whitespace still applies
+	code, stdout, _ = runCli(input, "--convention", "jsonml", "--compact", "--whitespace", "collapse")
+	require.Equal(t, 0, code)
+	require.Equal(t, `["p","Hello ",["b","world"]," again"]`+"\n", stdout)
+}
+
+func TestCliUsageErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"a.xml", "b.xml"}, "xml2json: too many arguments, expected at most one input file\n"},
+		{[]string{"--indent", "-1"}, "xml2json: -indent must not be negative\n"},
+		{[]string{"--convention", "xml"}, "xml2json: unknown -convention \"xml\", expected default, badgerfish, parker, gdata or jsonml\n"},
+		{[]string{"--mixed", "all"}, "xml2json: unknown -mixed mode \"all\", expected text or segments\n"},
+		{[]string{"--whitespace", "none"}, "xml2json: unknown -whitespace mode \"none\", expected trim, collapse or preserve\n"},
+		{[]string{"--text-key", ""}, "xml2json: -text-key must not be empty\n"},
+	}
+
+	for _, tt := range tests {
+		code, stdout, stderr := runCli(`<a/>`, tt.args...)
+		require.Equal(t, 2, code, tt.args)
+		require.Empty(t, stdout, tt.args)
+		require.Equal(t, tt.expected, stderr, tt.args)
+	}
+
+	code, _, stderr := runCli(`<a/>`, "--no-such-flag")
+	require.Equal(t, 2, code)
+	require.True(t, strings.HasPrefix(stderr, "flag provided but not defined: -no-such-flag\nUsage: xml2json"), stderr)
+}
@@ -0,0 +1,658 @@
+package tests
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal"
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/converter"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	parser2 "github.com/jdodson3106/goXml2Json/internal/parser"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestFile(t *testing.T, fileName string) *ast.Document {
+	l, err := lexer.New(string(loadDataFile(t, fileName)), lexer.XML)
+	require.NoError(t, err)
+	return parser2.New(l).ParseDocument()
+}
+
+func parseXml(t *testing.T, input string) *ast.Document {
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+	p := parser2.New(l)
+	doc := p.ParseDocument()
+	require.Empty(t, p.Errors())
+	return doc
+}
+
+func compactConverter() *converter.Converter {
+	return converter.New(converter.Options{})
+}
+
+// prefix returns a pointer to p for Options.AttributePrefix
+func prefix(p string) *string {
+	return &p
+}
+
+func tagNode(name string, value string, attrs map[string]string, children ...*ast.ElementTagNode) *ast.ElementTagNode {
+	tag := &ast.ElementTagNode{Token: token.Token{Type: token.TAG, Literal: name}}
+	if value != "" {
+		tag.Value = ast.ElementValueNode{Token: token.Token{Type: token.VALUE, Literal: value}, Value: value}
+	}
+	for k, v := range attrs {
+		tag.Attributes = append(tag.Attributes, &ast.ElementAttributeNode{
+			Key:   &ast.AttributeKeyNode{Token: token.Token{Type: token.KEY, Literal: k}, Value: k},
+			Value: &ast.AttributeValueNode{Token: token.Token{Type: token.VALUE, Literal: v}, Value: v},
+		})
+	}
+	tag.Elements = append(tag.Elements, children...)
+	return tag
+}
+
+func TestConvertTagDefinitionFile(t *testing.T) {
+	doc := parseTestFile(t, "tagDefTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"Justin","dob":"09-27-1989","phone":"8675309"}`, string(out))
+}
+
+func TestConvertAttributeFile(t *testing.T) {
+	doc := parseTestFile(t, "tagAttributeTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":{"@value":"Justin"},"dob":{"@value":"09-27-1989"},"ssn":{"@value":"999999999"}}`, string(out))
+}
+
+func TestConvertNestedElementsFile(t *testing.T) {
+	doc := parseTestFile(t, "nestedElementsTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"employee":{"@role":"programmer","name":"Justin","dob":"09-27-1989","phone":{"@type":"mobile","#text":"8675301"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertRepeatedRecordsFile(t *testing.T) {
+	doc := parseTestFile(t, "repeatedRecordsTest.xml")
+
+	node, err := compactConverter().Convert(doc)
+	require.NoError(t, err)
+
+	people := node.(*ast.JsonObjectNode).Get("people").(*ast.JsonObjectNode)
+	persons := people.Get("person").(*ast.JsonArrayNode)
+	require.Equal(t, 3, len(persons.Elements))
+
+	father := persons.Elements[0].(*ast.JsonObjectNode)
+	require.Equal(t,
+		`{"@role":"father","name":[{"@category":"given-name","#text":"Justin"},{"@category":"family-name","#text":"Dodson"}],"dob":"09-27-1989","ssn":"999-99-9994"}`,
+		string(converter.Encode(father, "")),
+	)
+}
+
+func TestConvertMixedContentFile(t *testing.T) {
+	doc := parseTestFile(t, "mixedContentTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"@class":"intro","#text":"Hello again goodbye","b":"world","i":"and"}}`, string(out))
+
+	out, err = converter.New(converter.Options{MixedContent: converter.MixedSegments}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"@class":"intro","#content":["Hello ",{"b":"world"}," again ",{"i":"and"}," goodbye"]}}`, string(out))
+}
+
+func TestConvertProseKeepsSpacesAroundInlineElements(t *testing.T) {
+	doc := parseTestFile(t, "proseTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"article":{"p":[{"#text":"Hello again","b":"world"},`+
+			`{"#text":"Read the , then yourself.","a":{"@href":"guide.html","#text":"guide"},"em":"try it"}]}}`,
+		string(out),
+	)
+
+	out, err = converter.New(converter.Options{MixedContent: converter.MixedSegments}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"article":{"p":[{"#content":["Hello ",{"b":"world"}," again"]},`+
+			`{"#content":["Read the ",{"a":{"@href":"guide.html","#text":"guide"}},", then ",{"em":"try it"}," yourself."]}]}}`,
+		string(out),
+	)
+}
+
+func TestConvertKeepsSpaceBetweenInlineElements(t *testing.T) {
+	input := `<p>Read <b>this</b> <i>now</i>.</p>`
+	doc := parseXml(t, input)
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"#text":"Read .","b":"this","i":"now"}}`, string(out))
+
+	out, err = converter.New(converter.Options{MixedContent: converter.MixedSegments}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"#content":["Read ",{"b":"this"}," ",{"i":"now"},"."]}}`, string(out))
+
+	c := converter.New(converter.Options{Convention: converter.ConventionJsonML})
+	out, err = c.ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `["p","Read ",["b","this"]," ",["i","now"],"."]`, string(out))
+	require.Equal(t, input, reverseJson(t, converter.Options{Convention: converter.ConventionJsonML}, string(out)))
+
+	// without other text the whitespace between the elements is indentation and is dropped
+	out, err = compactConverter().ToJson(parseXml(t, "<p>\n  <b>this</b>\n  <i>now</i>\n</p>"))
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"b":"this","i":"now"}}`, string(out))
+}
+
+func TestConvertJoinsTextAroundAdjacentInlineElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<p>x<b>a</b> <i>b</i>y</p>`, `{"p":{"#text":"x y","b":"a","i":"b"}}`},
+		{`<p>x<b>a</b> <i>b</i></p>`, `{"p":{"#text":"x ","b":"a","i":"b"}}`},
+		{`<p>Hello <b>a</b> <i>b</i> again</p>`, `{"p":{"#text":"Hello again","b":"a","i":"b"}}`},
+		{`<p>x<b>a</b><i>b</i>y</p>`, `{"p":{"#text":"xy","b":"a","i":"b"}}`},
+	}
+
+	for _, tt := range tests {
+		out, err := compactConverter().ToJson(parseXml(t, tt.input))
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, string(out), tt.input)
+	}
+}
+
+func TestConvertAppliesAttributeDefaults(t *testing.T) {
+	out, err := compactConverter().ToJson(parseXml(t, `<!DOCTYPE a [<!ATTLIST a id CDATA "7">]><a/>`))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"@id":"7"}}`, string(out))
+
+	out, err = compactConverter().ToJson(parseTestFile(t, "doctypeTest.xml"))
+	require.NoError(t, err)
+	require.Equal(t, `{"catalog":{"item":{"@id":"a1","@currency":"USD","@source":"feed>v2","#text":"Widget"}}}`, string(out))
+}
+
+func TestConvertComments(t *testing.T) {
+	doc := parseTestFile(t, "commentTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"person":{"name":"Justin","phone":"8675309"}}`, string(out))
+
+	out, err = converter.New(converter.Options{KeepComments: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"#comment":" Licensed under the MIT license ","person":{"#comment":[" given name only ","<ssn>000-00-0000</ssn>"],"name":"Justin","phone":"8675309"}}`,
+		string(out),
+	)
+
+	doc = parseTestFile(t, "mixedContentTest.xml")
+	doc.Elements[0].(*ast.ElementTagNode).Children = append(doc.Elements[0].(*ast.ElementTagNode).Children,
+		&ast.CommentNode{Token: token.Token{Type: token.COMMENT, Literal: "end"}, Value: "end"})
+
+	out, err = converter.New(converter.Options{MixedContent: converter.MixedSegments, KeepComments: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"@class":"intro","#content":["Hello ",{"b":"world"}," again ",{"i":"and"}," goodbye",{"#comment":"end"}]}}`, string(out))
+}
+
+func TestConvertCData(t *testing.T) {
+	doc := parseTestFile(t, "cdataTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"snippet":{"@lang":"html","title":"Escaping","body":"<p class=\"note\">Use &amp; for & </p>","query":"SELECT * FROM t WHERE a < 10 AND b <> ']]>'"}}`,
+		string(out),
+	)
+
+	out, err = converter.New(converter.Options{MarkCData: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"snippet":{"@lang":"html","title":"Escaping","body":{"#cdata":"<p class=\"note\">Use &amp; for & </p>"},"query":{"#cdata":["SELECT * FROM t WHERE a < 10 AND b <> ']]",">'"]}}}`,
+		string(out),
+	)
+}
+
+func TestConvertCDataInMixedContent(t *testing.T) {
+	l, err := lexer.New(`<p>x <![CDATA[<b>]]><i>y</i></p>`, lexer.XML)
+	require.NoError(t, err)
+	doc := parser2.New(l).ParseDocument()
+
+	tests := []struct {
+		opts     converter.Options
+		expected string
+	}{
+		{converter.Options{}, `{"p":{"#text":"x <b>","i":"y"}}`},
+		{converter.Options{MarkCData: true}, `{"p":{"#text":"x ","#cdata":"<b>","i":"y"}}`},
+		{converter.Options{MixedContent: converter.MixedSegments}, `{"p":{"#content":["x ","<b>",{"i":"y"}]}}`},
+		{converter.Options{MixedContent: converter.MixedSegments, MarkCData: true}, `{"p":{"#content":["x ",{"#cdata":"<b>"},{"i":"y"}]}}`},
+	}
+
+	for _, tt := range tests {
+		out, err := converter.New(tt.opts).ToJson(doc)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, string(out))
+	}
+}
+
+func TestConvertProcessingInstructions(t *testing.T) {
+	doc := parseTestFile(t, "declarationTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"people":{"person":"Justin"}}`, string(out))
+
+	out, err = converter.New(converter.Options{KeepProcInsts: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"?xml-stylesheet":"type=\"text/xsl\" href=\"people.xsl\"","people":{"?sort":"by=\"name\"","person":"Justin"}}`, string(out))
+}
+
+func TestConvertDecodesReferences(t *testing.T) {
+	doc := parseTestFile(t, "entityTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"company":{"@ticker":"T&","name":"AT&T","legal":"AT&T Inc","symbols":"<>&'\"","copyright":"©","smile":"😀"}}`,
+		string(out),
+	)
+}
+
+func TestConvertFreeFormText(t *testing.T) {
+	doc := parseTestFile(t, "freeTextTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"book":{"@title":"Hello, world!","@isbn":"978-0-13-468599-1","@note":"","author":"Justin Dodson","published":"09/27/1989",`+
+			`"summary":"Tokens like a + b = c, (parentheses) and \"quotes\" are just text.",`+
+			`"tagline":{"@title":"It's \"free\"  form","#text":"1 > 0 & done?"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertUnicodeNames(t *testing.T) {
+	doc := parseTestFile(t, "unicodeTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"kunden":{"kunde":{"@größe":"groß","@名前":"山田","straße":"Königsallee 1","名前":"山田 太郎","ciudad":"São Paulo"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertKeepsQualifiedNames(t *testing.T) {
+	doc := parseTestFile(t, "namespaceTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"soap:Envelope":{"@xmlns:soap":"http://schemas.xmlsoap.org/soap/envelope/","@xmlns":"urn:people","soap:Body":{"person":{`+
+			`"@xmlns:ext":"urn:ext","@ext:id":"7","@role":"father","@xml:lang":"en","name":"Justin","ext:nickname":{"@xmlns":"","#text":"JD"},"note":"none"}}}}`,
+		string(out),
+	)
+}
+
+func TestConvertBadgerFish(t *testing.T) {
+	doc := parseXml(t, `<alice xmlns="urn:a" xmlns:c="urn:c" id="1"><bob>david</bob><c:edgar xmlns="">frank</c:edgar><bob/></alice>`)
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionBadgerFish}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"alice":{"@id":"1","@xmlns":{"$":"urn:a","c":"urn:c"},`+
+			`"bob":[{"@xmlns":{"$":"urn:a","c":"urn:c"},"$":"david"},{"@xmlns":{"$":"urn:a","c":"urn:c"}}],`+
+			`"c:edgar":{"@xmlns":{"c":"urn:c"},"$":"frank"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertBadgerFishFile(t *testing.T) {
+	doc := parseTestFile(t, "nestedElementsTest.xml")
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionBadgerFish, InferTypes: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"employee":{"@role":"programmer","name":{"$":"Justin"},"dob":{"$":"09-27-1989"},"phone":{"@type":"mobile","$":8675301}}}`,
+		string(out),
+	)
+}
+
+func TestConvertParker(t *testing.T) {
+	doc := parseXml(t, `<a id="1">text<b x="y">1</b><b/><c><!-- n --><![CDATA[<d>]]></c><?pi x?></a>`)
+
+	opts := converter.Options{Convention: converter.ConventionParker, KeepComments: true, MarkCData: true, KeepProcInsts: true}
+	out, err := converter.New(opts).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"b":["1",null],"c":"<d>"}}`, string(out))
+}
+
+func TestConvertParkerFullTestFile(t *testing.T) {
+	doc := parseTestFile(t, "fullTestFile.xml")
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionParker}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"people":{"person":[`+
+			`{"name":["Justin","Dodson"],"dob":"09/27/1989","ssn":"999-99-9994"},`+
+			`{"name":["Diana","Dodson"],"dob":"03/04/1988","ssn":"999-99-9995"},`+
+			`{"name":["Jimmie","Dodson"],"dob":"08/31/2006","ssn":"999-99-99996/ssn>"},`+
+			`{"name":["Wyatt","Dodson"],"dob":"12/08/2009","ssn":"999-99-9997"},`+
+			`{"name":["Noah","Dodson"],"dob":"12/14/2012","ssn":"999-99-99998"},`+
+			`{"name":["Lilah","Dodson"],"dob":"01/27/2020","ssn":"999-99-9999"}]}}`,
+		string(out),
+	)
+}
+
+func TestConvertGData(t *testing.T) {
+	doc := parseXml(t, `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:openSearch="http://a9.com/-/spec/opensearchrss/1.0/">`+
+		`<openSearch:totalResults>2</openSearch:totalResults><title type="text">Hi</title>`+
+		`<entry><id>1</id></entry><entry><id>2</id><link rel="self" href="x"/></entry></feed>`)
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionGData, InferTypes: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"feed":{"xmlns":"http://www.w3.org/2005/Atom","xmlns$openSearch":"http://a9.com/-/spec/opensearchrss/1.0/",`+
+			`"openSearch$totalResults":{"$t":2},"title":{"type":"text","$t":"Hi"},`+
+			`"entry":[{"id":{"$t":1}},{"id":{"$t":2},"link":{"rel":"self","href":"x"}}]}}`,
+		string(out),
+	)
+}
+
+func TestConvertGDataNameCollision(t *testing.T) {
+	doc := parseXml(t, `<a id="1"><id>2</id></a>`)
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionGData}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"id":["1",{"$t":"2"}]}}`, string(out))
+}
+
+func TestConvertJsonML(t *testing.T) {
+	doc := parseXml(t, `<!-- c --><p class="intro" id="1">Hello <b>bold</b> and <i/><![CDATA[<raw>]]><?pi x?></p>`)
+
+	out, err := converter.New(converter.Options{Convention: converter.ConventionJsonML, InferTypes: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `["p",{"class":"intro","id":"1"},"Hello ",["b","bold"]," and ",["i"],"<raw>"]`, string(out))
+}
+
+func TestConvertJsonMLNeedsSingleRoot(t *testing.T) {
+	c := converter.New(converter.Options{Convention: converter.ConventionJsonML})
+
+	_, err := c.ToJson(&ast.Document{})
+	require.EqualError(t, err, "a JsonML document needs a root element")
+
+	_, err = c.ToJson(&ast.Document{Elements: []ast.ElementNode{tagNode("a", "1", nil), tagNode("b", "2", nil)}})
+	require.EqualError(t, err, "a JsonML document has a single root element, found b after a")
+}
+
+func TestConvertAttributePrefixAndTextKey(t *testing.T) {
+	doc := parseXml(t, `<a id="1">x<b role="r">y</b><id>2</id></a>`)
+
+	tests := []struct {
+		opts     converter.Options
+		expected string
+	}{
+		{converter.Options{}, `{"a":{"@id":"1","#text":"x","b":{"@role":"r","#text":"y"},"id":"2"}}`},
+		{converter.Options{AttributePrefix: prefix("-"), TextKey: "_"}, `{"a":{"-id":"1","_":"x","b":{"-role":"r","_":"y"},"id":"2"}}`},
+		{converter.Options{AttributePrefix: prefix("_"), TextKey: "value"}, `{"a":{"_id":"1","value":"x","b":{"_role":"r","value":"y"},"id":"2"}}`},
+		// without a prefix the attribute and the element share a key
+		{converter.Options{AttributePrefix: prefix("")}, `{"a":{"id":["1","2"],"#text":"x","b":{"role":"r","#text":"y"}}}`},
+	}
+
+	for _, tt := range tests {
+		out, err := converter.New(tt.opts).ToJson(doc)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, string(out))
+	}
+}
+
+func TestConvertAttributePrefixAndTextKeyCollisions(t *testing.T) {
+	tests := []struct {
+		opts     converter.Options
+		input    string
+		expected string
+	}{
+		{converter.Options{TextKey: "value"}, `<item id="1">x<value>3</value></item>`,
+			"element value can not be keyed by its name as it collides with the text key or attribute prefix"},
+		{converter.Options{AttributePrefix: prefix("_"), TextKey: "_text"}, `<item text="1">x</item>`,
+			`attribute text of element item can not be keyed "_text" as it collides with the text key`},
+		{converter.Options{AttributePrefix: prefix(""), TextKey: "value"}, `<item value="1"/>`,
+			`attribute value of element item can not be keyed "value" as it collides with the text key`},
+		{converter.Options{AttributePrefix: prefix("_")}, `<item><_id>1</_id></item>`,
+			"element _id can not be keyed by its name as it collides with the text key or attribute prefix"},
+	}
+
+	for _, tt := range tests {
+		_, err := converter.New(tt.opts).ToJson(parseXml(t, tt.input))
+		require.EqualError(t, err, tt.expected, tt.input)
+	}
+
+	// the same names are fine when they can not be mistaken for each other
+	out, err := converter.New(converter.Options{AttributePrefix: prefix("_"), TextKey: "_text"}).ToJson(parseXml(t, `<item id="1">x<value>3</value></item>`))
+	require.NoError(t, err)
+	require.Equal(t, `{"item":{"_id":"1","_text":"x","value":"3"}}`, string(out))
+}
+
+func TestConvertPreservedWhitespace(t *testing.T) {
+	input := `<a><b>  x  </b><c xml:space="preserve"> y </c></a>`
+
+	tests := []struct {
+		mode     parser2.WhitespaceMode
+		expected string
+	}{
+		{parser2.WhitespaceTrim, `{"a":{"b":"x","c":{"@xml:space":"preserve","#text":" y "}}}`},
+		{parser2.WhitespacePreserve, `{"a":{"b":"  x  ","c":{"@xml:space":"preserve","#text":" y "}}}`},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(input, lexer.XML)
+		require.NoError(t, err)
+		p := parser2.NewWithOptions(l, parser2.Options{Whitespace: tt.mode})
+		doc := p.ParseDocument()
+		require.Empty(t, p.Errors())
+
+		out, err := compactConverter().ToJson(doc)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, string(out))
+	}
+}
+
+func TestConvertSegmentsOnlyAffectMixedContent(t *testing.T) {
+	doc := parseTestFile(t, "nestedElementsTest.xml")
+
+	out, err := converter.New(converter.Options{MixedContent: converter.MixedSegments}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"employee":{"@role":"programmer","name":"Justin","dob":"09-27-1989","phone":{"@type":"mobile","#text":"8675301"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertInferTypes(t *testing.T) {
+	doc := &ast.Document{Elements: []ast.ElementNode{
+		tagNode("person", "", map[string]string{"age": "34"},
+			tagNode("height", "1.85", nil),
+			tagNode("married", "true", nil),
+			tagNode("zip", "01234", nil),
+			tagNode("price", "1.50", nil),
+			tagNode("ssn", "999-99-9994", nil),
+			tagNode("phone", "8675309", map[string]string{"ext": "12"}),
+		),
+	}}
+
+	out, err := converter.New(converter.Options{InferTypes: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"person":{"@age":34,"height":1.85,"married":true,"zip":"01234","price":"1.50","ssn":"999-99-9994","phone":{"@ext":12,"#text":8675309}}}`,
+		string(out),
+	)
+
+	out, err = converter.New(converter.Options{InferTypes: true, StringKeys: []string{"phone", "ext"}}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"person":{"@age":34,"height":1.85,"married":true,"zip":"01234","price":"1.50","ssn":"999-99-9994","phone":{"@ext":"12","#text":"8675309"}}}`,
+		string(out),
+	)
+}
+
+func TestConvertInferTypesKeepsLargeNumbers(t *testing.T) {
+	doc := parseXml(t, `<p><price>1234567.5</price><total>100000000000000000000.25</total><count>9007199254740993</count></p>`)
+
+	out, err := converter.New(converter.Options{InferTypes: true}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"p":{"price":1234567.5,"total":"100000000000000000000.25","count":9007199254740993}}`, string(out))
+}
+
+func TestConvertInferTypesIsOptIn(t *testing.T) {
+	doc := parseTestFile(t, "tagDefTest.xml")
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"Justin","dob":"09-27-1989","phone":"8675309"}`, string(out))
+
+	node, err := converter.New(converter.Options{InferTypes: true}).Convert(doc)
+	require.NoError(t, err)
+	phone := node.(*ast.JsonObjectNode).Get("phone").(*ast.JsonNumberNode)
+	require.Equal(t, token.TokenType(token.INT), phone.Token.Type)
+	require.Equal(t, int64(8675309), phone.Value)
+}
+
+func TestConvertEveryTestFileIsValidJson(t *testing.T) {
+	// rootKeys holds the top level keys every test file converts to, so that a file converting to {} or null fails
+	rootKeys := map[string][]string{
+		"cdataTest.xml":           {"snippet"},
+		"commentTest.xml":         {"person"},
+		"declarationTest.xml":     {"people"},
+		"doctypeTest.xml":         {"catalog"},
+		"entityTest.xml":          {"company"},
+		"freeTextTest.xml":        {"book"},
+		"fullTestFile.xml":        {"people"},
+		"mixedContentTest.xml":    {"p"},
+		"namespaceTest.xml":       {"soap:Envelope"},
+		"nestedElementsTest.xml":  {"employee"},
+		"proseTest.xml":           {"article"},
+		"repeatedRecordsTest.xml": {"people"},
+		"tagAttributeTest.xml":    {"name", "dob", "ssn"},
+		"tagDefTest.xml":          {"name", "dob", "phone"},
+		"unicodeTest.xml":         {"kunden"},
+		"whitespaceTest.xml":      {"customer"},
+	}
+
+	// fullTestFile.xml is broken on purpose for the error recovery tests and converts what the parser recovered
+	parseErrors := map[string][]string{
+		"fullTestFile.xml": {"19:4: Mismatching closing tag 'person' for element 'ssn' (in /people/person/ssn)"},
+	}
+
+	files, err := filepath.Glob(filepath.Join(testFilesDir, "*.xml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, path := range files {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			expected, ok := rootKeys[name]
+			require.True(t, ok, "no top level keys listed for %s", name)
+
+			l, err := lexer.New(string(loadDataFile(t, name)), lexer.XML)
+			require.NoError(t, err)
+			p := parser2.New(l)
+			doc := p.ParseDocument()
+			require.Equal(t, parseErrors[name], errorStrings(p.Errors()))
+
+			node, err := converter.New(converter.DefaultOptions()).Convert(doc)
+			require.NoError(t, err)
+
+			root, ok := node.(*ast.JsonObjectNode)
+			require.True(t, ok, "%s converted to %T", name, node)
+			var keys []string
+			for _, m := range root.Members {
+				keys = append(keys, m.Key)
+			}
+			require.Equal(t, expected, keys)
+
+			out, err := converter.New(converter.DefaultOptions()).ToJson(doc)
+			require.NoError(t, err)
+			require.True(t, json.Valid(out), "%s produced invalid json: %s", name, out)
+		})
+	}
+}
+
+func TestConvertMappingRules(t *testing.T) {
+	doc := &ast.Document{Elements: []ast.ElementNode{
+		tagNode("person", "", map[string]string{"role": "father"},
+			tagNode("name", "Justin", map[string]string{"category": "given-name"}),
+			tagNode("name", "Dodson", nil),
+			tagNode("dob", "09-27-1989", nil),
+			tagNode("spouse", "", nil),
+		),
+	}}
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"person":{"@role":"father","name":[{"@category":"given-name","#text":"Justin"},"Dodson"],"dob":"09-27-1989","spouse":null}}`,
+		string(out),
+	)
+}
+
+func TestConvertRepeatedRootsKeepFirstPosition(t *testing.T) {
+	doc := &ast.Document{Elements: []ast.ElementNode{
+		tagNode("a", "1", nil),
+		tagNode("b", "2", nil),
+		tagNode("a", "3", nil),
+	}}
+
+	out, err := compactConverter().ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":["1","3"],"b":"2"}`, string(out))
+}
+
+func TestConvertIndentedOutput(t *testing.T) {
+	doc := &ast.Document{Elements: []ast.ElementNode{
+		tagNode("person", "", map[string]string{"role": "father"}, tagNode("name", "Justin \"JD\"", nil)),
+	}}
+
+	out, err := converter.New(converter.Options{Indent: "  "}).ToJson(doc)
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"person\": {\n    \"@role\": \"father\",\n    \"name\": \"Justin \\\"JD\\\"\"\n  }\n}", string(out))
+}
+
+func TestConvertEmptyDocument(t *testing.T) {
+	out, err := compactConverter().ToJson(&ast.Document{})
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(out))
+
+	_, err = compactConverter().ToJson(nil)
+	require.Error(t, err)
+}
+
+func TestXmlObjectToJson(t *testing.T) {
+	x := &internal.XmlObject{}
+
+	obj, err := x.ToJson(`<name category="given-name">Justin</name>`)
+	require.NoError(t, err)
+	require.Equal(t, "object", obj.DataType())
+	require.Equal(t, `{"name":{"@category":"given-name","#text":"Justin"}}`, obj.String())
+
+	require.NoError(t, x.Parse(`<name id="1">Justin</name>`))
+	require.Equal(t, "name", x.Tag)
+	require.Equal(t, "Justin", x.Value)
+	require.Equal(t, "1", x.Id)
+	require.Equal(t, "Justin", x.Text)
+	require.Empty(t, x.Group)
+
+	require.NoError(t, x.Parse(`<p>Hello <b>world</b> again<![CDATA[!]]></p>`))
+	require.Equal(t, "p", x.Tag)
+	require.Equal(t, "Hello again!", x.Value)
+	require.Equal(t, "Hello world again!", x.Text)
+	require.Empty(t, x.Id)
+}
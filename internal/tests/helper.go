@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal/parser"
 )
 
 const testFilesDir = "../../data/testFiles"
@@ -21,3 +23,11 @@ func loadDataFile(t *testing.T, fileName string) []byte {
 	}
 	return fileData
 }
+
+func errorStrings(errs []*parser.ParseError) []string {
+	var messages []string
+	for _, e := range errs {
+		messages = append(messages, e.Error())
+	}
+	return messages
+}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal"
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/converter"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	parser2 "github.com/jdodson3106/goXml2Json/internal/parser"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+	"github.com/stretchr/testify/require"
+)
+
+func parseJson(t *testing.T, input string) (ast.JsonNode, []*parser2.ParseError) {
+	l, err := lexer.New(input, lexer.JSON)
+	require.NoError(t, err)
+
+	parser := parser2.NewJson(l)
+	return parser.ParseJson(), parser.Errors()
+}
+
+func TestParseJsonObject(t *testing.T) {
+	node, errs := parseJson(t, string(loadDataFile(t, "objectTest.json")))
+	require.Empty(t, errs)
+
+	people := node.(*ast.JsonObjectNode).Get("people").(*ast.JsonObjectNode)
+	require.Equal(t, "true", people.Get("@group").(*ast.JsonStringNode).Value)
+
+	persons := people.Get("person").(*ast.JsonArrayNode)
+	require.Equal(t, 2, len(persons.Elements))
+
+	justin := persons.Elements[0].(*ast.JsonObjectNode)
+	require.Equal(t, []string{"name", "age", "height", "married", "nickname"}, memberKeys(justin))
+	require.Equal(t, "Justin", justin.Get("name").(*ast.JsonStringNode).Value)
+	require.Equal(t, int64(34), justin.Get("age").(*ast.JsonNumberNode).Value)
+	require.Equal(t, 1.85, justin.Get("height").(*ast.JsonNumberNode).Value)
+	require.Equal(t, true, justin.Get("married").(*ast.JsonBoolNode).Value)
+	require.IsType(t, &ast.JsonNullNode{}, justin.Get("nickname"))
+
+	diana := persons.Elements[1].(*ast.JsonObjectNode)
+	require.Equal(t, `Diana "Di" Dodson`, diana.Get("name").(*ast.JsonStringNode).Value)
+	require.Empty(t, diana.Get("tags").(*ast.JsonArrayNode).Elements)
+	require.Empty(t, diana.Get("address").(*ast.JsonObjectNode).Members)
+}
+
+func TestParseJsonScalars(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"text"`, `"text"`},
+		{`-12`, `-12`},
+		// numbers are written with the literal they were read from
+		{`12.5e2`, `12.5e2`},
+		{`99999999999999999999`, `99999999999999999999`},
+		{`false`, `false`},
+		{`null`, `null`},
+		{` [1, "two", [3]] `, `[1,"two",[3]]`},
+	}
+
+	for _, tt := range tests {
+		node, errs := parseJson(t, tt.input)
+		require.Empty(t, errs, tt.input)
+		require.Equal(t, tt.expected, string(converter.Encode(node, "")))
+	}
+}
+
+func TestParseJsonErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "1:1: unexpected end of input"},
+		{`{"a" 1}`, `1:6: expected ':' after object key, got INT "1"`},
+		{`{"a": 1,}`, `1:9: expected a string object key, got } "}"`},
+		{`{a: 1}`, `1:2: expected a string object key, got illegal token "a"`},
+		{`[1 2]`, `1:4: expected ',' or ']' after array element, got INT "2"`},
+		{`{"a": [1}`, `1:9: expected ',' or ']' after array element, got } "}" (in /a)`},
+		{`"open`, `1:1: illegal token "unterminated string"`},
+		{`{} {}`, `1:4: expected end of input, got { "{"`},
+		{`[1,`, "1:4: unexpected end of input (in /1)"},
+	}
+
+	for _, tt := range tests {
+		node, errs := parseJson(t, tt.input)
+		require.Nil(t, node, tt.input)
+		require.Equal(t, []string{tt.expected}, errorStrings(errs), tt.input)
+	}
+}
+
+func TestJsonParseErrorPaths(t *testing.T) {
+	_, errs := parseJson(t, `{"people": {"person": [{"name": "Justin"}, {"name" "Diana"}]}}`)
+	require.Equal(t, 1, len(errs))
+
+	e := errs[0]
+	require.Equal(t, parser2.ErrUnexpectedToken, e.Kind)
+	require.Equal(t, []string{"people", "person", "1"}, e.Path)
+	require.Equal(t, []token.TokenType{token.COLON}, e.Expected)
+	require.Equal(t, token.Token{Type: token.STRING, Literal: "Diana", Pos: token.Position{Offset: 51, Char: 51, Line: 1, Column: 52}}, e.Actual)
+}
+
+func TestParseDispatchesOnDocumentType(t *testing.T) {
+	node, err := parser2.Parse(`{"name": "Justin"}`, parser2.JSON)
+	require.NoError(t, err)
+	require.IsType(t, &ast.JsonObjectNode{}, node)
+
+	node, err = parser2.Parse(`<name>Justin</name>`, parser2.XML)
+	require.NoError(t, err)
+	require.IsType(t, &ast.Document{}, node)
+
+	_, err = parser2.Parse(`{"name"}`, parser2.JSON)
+	require.Error(t, err)
+
+	_, err = parser2.Parse(`{}`, "yaml")
+	require.Error(t, err)
+}
+
+func TestJsonObjectParse(t *testing.T) {
+	obj := &internal.JsonObject{}
+	require.NoError(t, obj.Parse(`[1, 2]`))
+	require.Equal(t, "array", obj.DataType())
+	require.Equal(t, `[1,2]`, obj.String())
+
+	require.Error(t, obj.Parse(`[1, 2`))
+}
+
+func memberKeys(obj *ast.JsonObjectNode) []string {
+	var keys []string
+	for _, m := range obj.Members {
+		keys = append(keys, m.Key)
+	}
+	return keys
+}
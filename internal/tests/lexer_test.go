@@ -1,8 +1,16 @@
 package tests
 
 import (
-	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
 
 	"github.com/jdodson3106/goXml2Json/internal/token"
 	"github.com/stretchr/testify/require"
@@ -111,6 +119,7 @@ func TestNestedElementsNextToken(t *testing.T) {
 		{token.OPEN_ANGLE, "<"},
 		{token.TAG, "person"},
 		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "\n\t\t"}, // indentation is text as well
 
 		{token.OPEN_ANGLE, "<"},
 		{token.TAG, "name"},
@@ -125,6 +134,7 @@ func TestNestedElementsNextToken(t *testing.T) {
 		{token.XML_TERMINATOR, "/"},
 		{token.TAG, "name"},
 		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "\n\t\t"},
 
 		{token.OPEN_ANGLE, "<"},
 		{token.TAG, "name"},
@@ -139,14 +149,614 @@ func TestNestedElementsNextToken(t *testing.T) {
 		{token.XML_TERMINATOR, "/"},
 		{token.TAG, "name"},
 		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "\n\t"},
 
 		{token.OPEN_ANGLE, "<"},
 		{token.XML_TERMINATOR, "/"},
 		{token.TAG, "person"},
 		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "\n\t"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestSingleCharacterClosingTagNextToken(t *testing.T) {
+	xmlInput := `<a>1</a>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "1"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.EOF, ""},
 	}
 
 	lex, err := lexer.New(xmlInput, lexer.XML)
 	require.NoError(t, err)
 	runNextTokenChecks(lex, testCases, t)
 }
+
+func TestMixedContentNextToken(t *testing.T) {
+	xmlInput := `<p>Hello <b>world</b> again</p>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "p"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "Hello "},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "b"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "world"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "b"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, " again"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "p"},
+		{token.CLOSE_ANGLE, ">"},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestFreeFormValueNextToken(t *testing.T) {
+	xmlInput := `<a title="Hello, world!" q='say "hi"' e="">09/27/1989 & more</a>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.KEY, "title"},
+		{token.EQUAL, "="},
+		{token.QUOTE, "\""},
+		{token.VALUE, "Hello, world!"},
+		{token.QUOTE, "\""},
+		{token.KEY, "q"},
+		{token.EQUAL, "="},
+		{token.SINGLE_QUOTE, "'"},
+		{token.VALUE, `say "hi"`},
+		{token.SINGLE_QUOTE, "'"},
+		{token.KEY, "e"},
+		{token.EQUAL, "="},
+		{token.QUOTE, "\""},
+		{token.VALUE, ""},
+		{token.QUOTE, "\""},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "09/27/1989 & more"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestUnclosedValueNextToken(t *testing.T) {
+	// a value missing its closing quote ends at the other kind of quote or at the next tag
+	xmlInput := `<a b="1'><c d="2><e/>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.KEY, "b"},
+		{token.EQUAL, "="},
+		{token.QUOTE, "\""},
+		{token.VALUE, "1"},
+		{token.SINGLE_QUOTE, "'"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "c"},
+		{token.KEY, "d"},
+		{token.EQUAL, "="},
+		{token.QUOTE, "\""},
+		{token.VALUE, "2>"},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "e"},
+		{token.XML_TERMINATOR, "/"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestUnicodeNameNextToken(t *testing.T) {
+	xmlInput := `<größe 名前="山田">Königsallee</größe><1a/>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "größe"},
+		{token.KEY, "名前"},
+		{token.EQUAL, "="},
+		{token.QUOTE, "\""},
+		{token.VALUE, "山田"},
+		{token.QUOTE, "\""},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "Königsallee"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "größe"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.OPEN_ANGLE, "<"},
+		{token.ILLEGAL, "1"}, // a name can not start with a digit
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestCommentNextToken(t *testing.T) {
+	xmlInput := `<!-- header --><a><!--<b>x</b>-->1</a><!-- open`
+
+	testCases := []TokenTestCase{
+		{token.COMMENT, " header "},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.COMMENT, "<b>x</b>"},
+		{token.VALUE, "1"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.ILLEGAL, "unterminated comment"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestCDataNextToken(t *testing.T) {
+	xmlInput := `<a><![CDATA[<b>&amp; ]] >]]>1</a><![CDATA[ open`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.CDATA, "<b>&amp; ]] >"},
+		{token.VALUE, "1"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.ILLEGAL, "unterminated CDATA section"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestProcessingInstructionNextToken(t *testing.T) {
+	xmlInput := `<?xml version="1.0"?><a><?sort by="name"?>1</a>`
+
+	testCases := []TokenTestCase{
+		{token.PI, `xml version="1.0"`},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.PI, `sort by="name"`},
+		{token.VALUE, "1"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestDoctypeNextToken(t *testing.T) {
+	xmlInput := `<!DOCTYPE a [<!ENTITY b "x>y"><!-- ]> -->]><a/><!DOCTYPE a`
+
+	testCases := []TokenTestCase{
+		{token.DOCTYPE, ` a [<!ENTITY b "x>y"><!-- ]> -->]`},
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.XML_TERMINATOR, "/"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.ILLEGAL, "unterminated DOCTYPE"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestReferencesNextToken(t *testing.T) {
+	xmlInput := `<a b="&lt;T&amp;">&#169;AT&T</a>`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.KEY, "b"},
+		{token.EQUAL, "="},
+		{token.QUOTE, `"`},
+		{token.VALUE, "&lt;T&amp;"},
+		{token.QUOTE, `"`},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "&#169;AT&T"},
+		{token.OPEN_ANGLE, "<"},
+		{token.XML_TERMINATOR, "/"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"no declaration", []byte("<a>caf\u00e9</a>"), "<a>caf\u00e9</a>"},
+		{"utf-8 bom", []byte("\xEF\xBB\xBF<a/>"), "<a/>"},
+		{"latin1", []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><a>caf` + "\xe9</a>"), `<?xml version="1.0" encoding="ISO-8859-1"?><a>caf` + "\u00e9</a>"},
+		{"windows-1252", []byte("<?xml version='1.0' encoding = 'windows-1252'?><a>\x80\x96</a>"), "<?xml version='1.0' encoding = 'windows-1252'?><a>\u20ac\u2013</a>"},
+		{"ascii", []byte(`<?xml version="1.0" encoding="us-ascii"?><a/>`), `<?xml version="1.0" encoding="us-ascii"?><a/>`},
+		{"utf-16le", []byte("\xFF\xFE<\x00a\x00/\x00>\x00"), "<a/>"},
+		{"utf-16be", []byte("\xFE\xFF\x00<\x00a\x00/\x00>"), "<a/>"},
+	}
+
+	for _, tt := range tests {
+		decoded, err := lexer.Decode(tt.input)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.expected, decoded, tt.name)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("<a>caf\xe9</a>"), "input is not valid UTF-8"},
+		{[]byte(`<?xml version="1.0" encoding="US-ASCII"?><a>` + "\xe9</a>"), "byte 0xE9 at offset 44 is not valid US-ASCII"},
+		{[]byte(`<?xml version="1.0" encoding="EBCDIC"?><a/>`), `unsupported encoding "EBCDIC"`},
+		{[]byte(`<?xml version="1.0" encoding="UTF-16"?><a/>`), "UTF-16 input must start with a byte order mark"},
+	}
+
+	for _, tt := range tests {
+		_, err := lexer.Decode(tt.input)
+		require.EqualError(t, err, tt.expected)
+	}
+}
+
+func TestJsonNextToken(t *testing.T) {
+	jsonInput := `{"name": "Justin\n\"JD\"", "age": -34, "height": 1.85e0, "ok": true, "none": null, "list": [false]}`
+
+	testCases := []TokenTestCase{
+		{token.OPEN_CURLY, "{"},
+		{token.STRING, "name"},
+		{token.COLON, ":"},
+		{token.STRING, "Justin\n\"JD\""},
+		{token.COMMA, ","},
+		{token.STRING, "age"},
+		{token.COLON, ":"},
+		{token.INT, "-34"},
+		{token.COMMA, ","},
+		{token.STRING, "height"},
+		{token.COLON, ":"},
+		{token.FLOAT, "1.85e0"},
+		{token.COMMA, ","},
+		{token.STRING, "ok"},
+		{token.COLON, ":"},
+		{token.BOOL, "true"},
+		{token.COMMA, ","},
+		{token.STRING, "none"},
+		{token.COLON, ":"},
+		{token.NULL, "null"},
+		{token.COMMA, ","},
+		{token.STRING, "list"},
+		{token.COLON, ":"},
+		{token.OPEN_SQUARE, "["},
+		{token.BOOL, "false"},
+		{token.CLOSE_SQUARE, "]"},
+		{token.CLOSE_CURLY, "}"},
+		{token.EOF, ""},
+	}
+
+	lex, err := lexer.New(jsonInput, lexer.JSON)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestJsonStringEscapesNextToken(t *testing.T) {
+	jsonInput := `"café 😀 \/ \\" "bad\q" 01 tru`
+
+	testCases := []TokenTestCase{
+		{token.STRING, "café 😀 / \\"},
+		{token.ILLEGAL, "invalid escape sequence in string"},
+		{token.ILLEGAL, "01"},
+		{token.ILLEGAL, "tru"},
+	}
+
+	lex, err := lexer.New(jsonInput, lexer.JSON)
+	require.NoError(t, err)
+	runNextTokenChecks(lex, testCases, t)
+}
+
+func TestTokenPositions(t *testing.T) {
+	xmlInput := "<person>\n\t<name category=\"given-name\">Justin</name>\r\n</person>"
+
+	expected := []struct {
+		literal string
+		pos     token.Position
+	}{
+		{"<", token.Position{Offset: 0, Char: 0, Line: 1, Column: 1}},
+		{"person", token.Position{Offset: 1, Char: 1, Line: 1, Column: 2}},
+		{">", token.Position{Offset: 7, Char: 7, Line: 1, Column: 8}},
+		{"\n\t", token.Position{Offset: 8, Char: 8, Line: 1, Column: 9}},
+		{"<", token.Position{Offset: 10, Char: 10, Line: 2, Column: 2}},
+		{"name", token.Position{Offset: 11, Char: 11, Line: 2, Column: 3}},
+		{"category", token.Position{Offset: 16, Char: 16, Line: 2, Column: 8}},
+		{"=", token.Position{Offset: 24, Char: 24, Line: 2, Column: 16}},
+		{"\"", token.Position{Offset: 25, Char: 25, Line: 2, Column: 17}},
+		{"given-name", token.Position{Offset: 26, Char: 26, Line: 2, Column: 18}},
+		{"\"", token.Position{Offset: 36, Char: 36, Line: 2, Column: 28}},
+		{">", token.Position{Offset: 37, Char: 37, Line: 2, Column: 29}},
+		{"Justin", token.Position{Offset: 38, Char: 38, Line: 2, Column: 30}},
+		{"<", token.Position{Offset: 44, Char: 44, Line: 2, Column: 36}},
+		{"/", token.Position{Offset: 45, Char: 45, Line: 2, Column: 37}},
+		{"name", token.Position{Offset: 46, Char: 46, Line: 2, Column: 38}},
+		{">", token.Position{Offset: 50, Char: 50, Line: 2, Column: 42}},
+		{"\r\n", token.Position{Offset: 51, Char: 51, Line: 2, Column: 43}},
+		{"<", token.Position{Offset: 53, Char: 53, Line: 3, Column: 1}},
+		{"/", token.Position{Offset: 54, Char: 54, Line: 3, Column: 2}},
+		{"person", token.Position{Offset: 55, Char: 55, Line: 3, Column: 3}},
+		{">", token.Position{Offset: 61, Char: 61, Line: 3, Column: 9}},
+		{"", token.Position{Offset: 62, Char: 62, Line: 3, Column: 10}},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+
+	for i, tt := range expected {
+		tok := lex.NextToken()
+		require.Equal(t, tt.literal, tok.Literal, "tests[%d]", i)
+		require.Equal(t, tt.pos, tok.Pos, "tests[%d] - %q", i, tt.literal)
+	}
+}
+
+func TestUnicodeTokenPositions(t *testing.T) {
+	// offsets count bytes while chars and columns count the unicode chars
+	xmlInput := "<名前 größe=\"ß\">\n\t山田</名前>"
+
+	expected := []struct {
+		literal string
+		pos     token.Position
+	}{
+		{"<", token.Position{Offset: 0, Char: 0, Line: 1, Column: 1}},
+		{"名前", token.Position{Offset: 1, Char: 1, Line: 1, Column: 2}},
+		{"größe", token.Position{Offset: 8, Char: 4, Line: 1, Column: 5}},
+		{"=", token.Position{Offset: 15, Char: 9, Line: 1, Column: 10}},
+		{"\"", token.Position{Offset: 16, Char: 10, Line: 1, Column: 11}},
+		{"ß", token.Position{Offset: 17, Char: 11, Line: 1, Column: 12}},
+		{"\"", token.Position{Offset: 19, Char: 12, Line: 1, Column: 13}},
+		{">", token.Position{Offset: 20, Char: 13, Line: 1, Column: 14}},
+		{"\n\t山田", token.Position{Offset: 21, Char: 14, Line: 1, Column: 15}},
+		{"<", token.Position{Offset: 29, Char: 18, Line: 2, Column: 4}},
+		{"/", token.Position{Offset: 30, Char: 19, Line: 2, Column: 5}},
+		{"名前", token.Position{Offset: 31, Char: 20, Line: 2, Column: 6}},
+		{">", token.Position{Offset: 37, Char: 22, Line: 2, Column: 8}},
+		{"", token.Position{Offset: 38, Char: 23, Line: 2, Column: 9}},
+	}
+
+	lex, err := lexer.New(xmlInput, lexer.XML)
+	require.NoError(t, err)
+
+	for i, tt := range expected {
+		tok := lex.NextToken()
+		require.Equal(t, tt.literal, tok.Literal, "tests[%d]", i)
+		require.Equal(t, tt.pos, tok.Pos, "tests[%d] - %q", i, tt.literal)
+	}
+}
+
+func TestIsName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"person", true},
+		{"_id", true},
+		{"ns:tag", true},
+		{"größe", true},
+		{"名前", true},
+		{"a-b.c·d", true},
+		{"", false},
+		{"1a", false},
+		{"-a", false},
+		{"a b", false},
+		{"a&b", false},
+		{"×", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, lexer.IsName(tt.name), tt.name)
+	}
+}
+
+// lexAll returns every token of l up to and including EOF
+func lexAll(l *lexer.Lexer) []token.Token {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+func TestNewReaderMatchesNew(t *testing.T) {
+	// a long document crosses many buffer refills, and a comment longer than the buffer has to fit whole
+	var long strings.Builder
+	long.WriteString("<people>")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&long, "\n\t<person id=\"%d\">Jürgen &amp; 山田 <b>%d</b></person>", i, i)
+	}
+	long.WriteString("<!--" + strings.Repeat("long comment ", 10000) + "--></people>")
+
+	inputs := map[string]string{"long": long.String()}
+	for _, f := range []string{"fullTestFile.xml", "freeTextTest.xml", "doctypeTest.xml", "unicodeTest.xml", "objectTest.json"} {
+		inputs[f] = string(loadDataFile(t, f))
+	}
+
+	for name, input := range inputs {
+		lexType := lexer.XML
+		if strings.HasSuffix(name, ".json") {
+			lexType = lexer.JSON
+		}
+
+		l, err := lexer.New(input, lexType)
+		require.NoError(t, err)
+		expected := lexAll(l)
+
+		readers := map[string]io.Reader{
+			"whole":    strings.NewReader(input),
+			"one byte": iotest.OneByteReader(strings.NewReader(input)),
+			"half":     iotest.HalfReader(strings.NewReader(input)),
+		}
+		for readerName, r := range readers {
+			l, err := lexer.NewReader(r, lexType)
+			require.NoError(t, err)
+			require.Equal(t, expected, lexAll(l), "%s read by %s", name, readerName)
+			require.NoError(t, l.Err())
+		}
+	}
+}
+
+// repeatReader reads prefix, then record n times, then suffix, without holding it all in memory
+type repeatReader struct {
+	prefix, record, suffix string
+	n                      int
+	pending                string
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	for r.pending == "" {
+		switch {
+		case r.prefix != "":
+			r.pending, r.prefix = r.prefix, ""
+		case r.n > 0:
+			r.pending, r.n = r.record, r.n-1
+		case r.suffix != "":
+			r.pending, r.suffix = r.suffix, ""
+		default:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func TestNewReaderMemoryDoesNotGrowWithInput(t *testing.T) {
+	record := "<person role=\"son\"><name category=\"given-name\">Jimmie</name><dob>08/31/2006</dob></person>\n"
+	const records = 250000 // about 22MB of xml
+
+	l, err := lexer.NewReader(&repeatReader{prefix: "<people>", record: record, suffix: "</people>", n: records}, lexer.XML)
+	require.NoError(t, err)
+
+	var stats runtime.MemStats
+	var maxHeap uint64 // the most memory still in use, measured after collecting garbage
+	count := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			t.Fatalf("illegal token %q at %s", tok.Literal, tok.Pos)
+		}
+		if count++; count%1000000 == 0 {
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			maxHeap = max(maxHeap, stats.HeapAlloc)
+		}
+	}
+	require.Equal(t, 3+records*34+4, count)
+	require.Less(t, maxHeap, uint64(4<<20))
+}
+
+// errReader fails after reading its input
+type errReader struct {
+	input string
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.input == "" {
+		return 0, errors.New("disk on fire")
+	}
+	n := copy(p, r.input)
+	r.input = r.input[n:]
+	return n, nil
+}
+
+func TestNewReaderError(t *testing.T) {
+	l, err := lexer.NewReader(&errReader{input: "<a>1"}, lexer.XML)
+	require.NoError(t, err)
+
+	testCases := []TokenTestCase{
+		{token.OPEN_ANGLE, "<"},
+		{token.TAG, "a"},
+		{token.CLOSE_ANGLE, ">"},
+		{token.VALUE, "1"},
+		{token.EOF, ""},
+	}
+	runNextTokenChecks(l, testCases, t)
+	require.EqualError(t, l.Err(), "disk on fire")
+}
+
+func TestNewDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"latin1", []byte(`<?xml version="1.0" encoding="latin1"?><a>caf` + "\xe9</a>"), `<?xml version="1.0" encoding="latin1"?><a>caf` + "é</a>"},
+		{"utf-16le", []byte("\xFF\xFE<\x00a\x00>\x00=\xd8\x00\xde<\x00/\x00a\x00>\x00"), "<a>\U0001F600</a>"},
+		{"unpaired surrogate", []byte("\xFE\xFF\xd8\x3d\x00a"), "�a"},
+	}
+
+	for _, tt := range tests {
+		r, err := lexer.NewDecoder(iotest.OneByteReader(bytes.NewReader(tt.input)))
+		require.NoError(t, err, tt.name)
+
+		decoded, err := io.ReadAll(iotest.OneByteReader(r))
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.expected, string(decoded), tt.name)
+	}
+
+	r, err := lexer.NewDecoder(bytes.NewReader([]byte("\xFF\xFE<\x00a")))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.EqualError(t, err, "UTF-16 input has an odd number of bytes")
+}
@@ -1,11 +1,16 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	parser2 "github.com/jdodson3106/goXml2Json/internal/parser"
 	"github.com/jdodson3106/goXml2Json/internal/token"
 	"testing"
 
 	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/converter"
 	"github.com/jdodson3106/goXml2Json/internal/lexer"
 	"github.com/stretchr/testify/require"
 )
@@ -38,51 +43,93 @@ func TestTagDefinition(t *testing.T) {
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "name",
+				Pos:     token.Position{Offset: 1, Char: 1, Line: 1, Column: 2},
 			},
+			Name: ast.Name{Local: "name"},
 			Value: ast.ElementValueNode{
 				Token: token.Token{
 					Type:    token.VALUE,
 					Literal: "Justin",
+					Pos:     token.Position{Offset: 6, Char: 6, Line: 1, Column: 7},
 				},
 				Value: "Justin",
 			},
+			Children: []ast.ElementNode{
+				&ast.ElementValueNode{
+					Token: token.Token{
+						Type:    token.VALUE,
+						Literal: "Justin",
+						Pos:     token.Position{Offset: 6, Char: 6, Line: 1, Column: 7},
+					},
+					Value: "Justin",
+				},
+			},
 			EndToken: token.Token{
 				Type:    token.TAG,
 				Literal: "name",
+				Pos:     token.Position{Offset: 14, Char: 14, Line: 1, Column: 15},
 			},
 		},
 		{
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "dob",
+				Pos:     token.Position{Offset: 21, Char: 21, Line: 2, Column: 2},
 			},
+			Name: ast.Name{Local: "dob"},
 			Value: ast.ElementValueNode{
 				Token: token.Token{
 					Type:    token.VALUE,
 					Literal: "09-27-1989",
+					Pos:     token.Position{Offset: 25, Char: 25, Line: 2, Column: 6},
 				},
 				Value: "09-27-1989",
 			},
+			Children: []ast.ElementNode{
+				&ast.ElementValueNode{
+					Token: token.Token{
+						Type:    token.VALUE,
+						Literal: "09-27-1989",
+						Pos:     token.Position{Offset: 25, Char: 25, Line: 2, Column: 6},
+					},
+					Value: "09-27-1989",
+				},
+			},
 			EndToken: token.Token{
 				Type:    token.TAG,
 				Literal: "dob",
+				Pos:     token.Position{Offset: 37, Char: 37, Line: 2, Column: 18},
 			},
 		},
 		{
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "phone",
+				Pos:     token.Position{Offset: 43, Char: 43, Line: 3, Column: 2},
 			},
+			Name: ast.Name{Local: "phone"},
 			Value: ast.ElementValueNode{
 				Token: token.Token{
 					Type:    token.VALUE,
 					Literal: "8675309",
+					Pos:     token.Position{Offset: 49, Char: 49, Line: 3, Column: 8},
 				},
 				Value: "8675309",
 			},
+			Children: []ast.ElementNode{
+				&ast.ElementValueNode{
+					Token: token.Token{
+						Type:    token.VALUE,
+						Literal: "8675309",
+						Pos:     token.Position{Offset: 49, Char: 49, Line: 3, Column: 8},
+					},
+					Value: "8675309",
+				},
+			},
 			EndToken: token.Token{
 				Type:    token.TAG,
 				Literal: "phone",
+				Pos:     token.Position{Offset: 58, Char: 58, Line: 3, Column: 17},
 			},
 		},
 	}
@@ -110,20 +157,25 @@ func TestAttributeDefinition(t *testing.T) {
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "name",
+				Pos:     token.Position{Offset: 1, Char: 1, Line: 1, Column: 2},
 			},
+			Name: ast.Name{Local: "name"},
 			Attributes: []*ast.ElementAttributeNode{
 				{
 					Key: &ast.AttributeKeyNode{
 						Token: token.Token{
 							Type:    token.KEY,
 							Literal: "value",
+							Pos:     token.Position{Offset: 6, Char: 6, Line: 1, Column: 7},
 						},
 						Value: "value",
+						Name:  ast.Name{Local: "value"},
 					},
 					Value: &ast.AttributeValueNode{
 						Token: token.Token{
 							Type:    token.VALUE,
 							Literal: "Justin",
+							Pos:     token.Position{Offset: 13, Char: 13, Line: 1, Column: 14},
 						},
 						Value: "Justin",
 					},
@@ -132,26 +184,32 @@ func TestAttributeDefinition(t *testing.T) {
 			EndToken: token.Token{
 				Type:    token.CLOSE_ANGLE,
 				Literal: ">",
+				Pos:     token.Position{Offset: 22, Char: 22, Line: 1, Column: 23},
 			},
 		},
 		{
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "dob",
+				Pos:     token.Position{Offset: 25, Char: 25, Line: 2, Column: 2},
 			},
+			Name: ast.Name{Local: "dob"},
 			Attributes: []*ast.ElementAttributeNode{
 				{
 					Key: &ast.AttributeKeyNode{
 						Token: token.Token{
 							Type:    token.KEY,
 							Literal: "value",
+							Pos:     token.Position{Offset: 29, Char: 29, Line: 2, Column: 6},
 						},
 						Value: "value",
+						Name:  ast.Name{Local: "value"},
 					},
 					Value: &ast.AttributeValueNode{
 						Token: token.Token{
 							Type:    token.VALUE,
 							Literal: "09-27-1989",
+							Pos:     token.Position{Offset: 36, Char: 36, Line: 2, Column: 13},
 						},
 						Value: "09-27-1989",
 					},
@@ -160,26 +218,32 @@ func TestAttributeDefinition(t *testing.T) {
 			EndToken: token.Token{
 				Type:    token.TAG,
 				Literal: "dob",
+				Pos:     token.Position{Offset: 50, Char: 50, Line: 2, Column: 27},
 			},
 		},
 		{
 			Token: token.Token{
 				Type:    token.TAG,
 				Literal: "ssn",
+				Pos:     token.Position{Offset: 56, Char: 56, Line: 3, Column: 2},
 			},
+			Name: ast.Name{Local: "ssn"},
 			Attributes: []*ast.ElementAttributeNode{
 				{
 					Key: &ast.AttributeKeyNode{
 						Token: token.Token{
 							Type:    token.KEY,
 							Literal: "value",
+							Pos:     token.Position{Offset: 60, Char: 60, Line: 3, Column: 6},
 						},
 						Value: "value",
+						Name:  ast.Name{Local: "value"},
 					},
 					Value: &ast.AttributeValueNode{
 						Token: token.Token{
 							Type:    token.VALUE,
 							Literal: "999999999",
+							Pos:     token.Position{Offset: 67, Char: 67, Line: 3, Column: 13},
 						},
 						Value: "999999999",
 					},
@@ -188,6 +252,7 @@ func TestAttributeDefinition(t *testing.T) {
 			EndToken: token.Token{
 				Type:    token.CLOSE_ANGLE,
 				Literal: ">",
+				Pos:     token.Position{Offset: 78, Char: 78, Line: 3, Column: 24},
 			},
 		},
 	}
@@ -197,3 +262,751 @@ func TestAttributeDefinition(t *testing.T) {
 		require.Equal(t, &tt, el)
 	}
 }
+
+func TestNestedElements(t *testing.T) {
+	input := string(loadDataFile(t, "nestedElementsTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+	require.Equal(t, 1, len(doc.Elements))
+
+	employee := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, "employee", employee.Token.Literal)
+	require.Equal(t, "employee", employee.EndToken.Literal)
+	require.Equal(t, 3, len(employee.Elements))
+
+	expected := []struct {
+		tag   string
+		value string
+	}{
+		{"name", "Justin"},
+		{"dob", "09-27-1989"},
+		{"phone", "8675301"},
+	}
+	for i, tt := range expected {
+		child := employee.Elements[i]
+		require.Equal(t, tt.tag, child.Token.Literal)
+		require.Equal(t, tt.tag, child.EndToken.Literal)
+		require.Equal(t, tt.value, child.Value.Value)
+	}
+	require.Equal(t, "type", employee.Elements[2].Attributes[0].Key.Value)
+}
+
+func TestRepeatedSiblingElements(t *testing.T) {
+	input := string(loadDataFile(t, "repeatedRecordsTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+	require.Equal(t, 1, len(doc.Elements))
+
+	people := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, 3, len(people.Elements))
+
+	roles := []string{"father", "mother", "son"}
+	for i, person := range people.Elements {
+		require.Equal(t, "person", person.Token.Literal)
+		require.Equal(t, roles[i], person.Attributes[0].Value.Value)
+		require.Equal(t, 4, len(person.Elements))
+
+		for j, tag := range []string{"name", "name", "dob", "ssn"} {
+			require.Equal(t, tag, person.Elements[j].Token.Literal)
+		}
+	}
+}
+
+func TestDeeplyNestedElements(t *testing.T) {
+	l, err := lexer.New(`<a><b><c><d>1</d><d>2</d></c></b><e/></a>`, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	a := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, 2, len(a.Elements))
+	require.Equal(t, "b", a.Elements[0].Token.Literal)
+	require.Equal(t, "e", a.Elements[1].Token.Literal)
+
+	c := a.Elements[0].Elements[0]
+	require.Equal(t, "c", c.Token.Literal)
+	require.Equal(t, 2, len(c.Elements))
+	require.Equal(t, "1", c.Elements[0].Value.Value)
+	require.Equal(t, "2", c.Elements[1].Value.Value)
+}
+
+func TestMismatchedClosingTag(t *testing.T) {
+	l, err := lexer.New(`<a><b>1</c></a>`, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	parser.ParseDocument()
+	require.Contains(t, errorStrings(parser.Errors()), "1:10: Mismatching closing tag 'c' for element 'b' (in /a/b)")
+}
+
+func TestMixedContent(t *testing.T) {
+	input := string(loadDataFile(t, "mixedContentTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	p := doc.Elements[0].(*ast.ElementTagNode)
+	require.True(t, p.HasMixedContent())
+	// only the start and end of the content are trimmed, the spaces around inline elements are kept
+	require.Equal(t, "Hello again goodbye", p.Value.Value)
+	require.Equal(t, 2, len(p.Elements))
+	require.Equal(t, 5, len(p.Children))
+
+	expected := []string{"Hello ", "b", " again ", "i", " goodbye"}
+	for i, child := range p.Children {
+		require.Equal(t, expected[i], child.TokenLiteral())
+	}
+	require.IsType(t, &ast.ElementValueNode{}, p.Children[0])
+	require.IsType(t, &ast.ElementTagNode{}, p.Children[1])
+	require.Same(t, p.Elements[1], p.Children[3])
+}
+
+func TestLongMixedContentValue(t *testing.T) {
+	const segments = 100000
+
+	var builder strings.Builder
+	builder.WriteString("<r>")
+	for i := 0; i < segments; i++ {
+		builder.WriteString("t <c/>\n")
+	}
+	builder.WriteString("</r>")
+
+	l, err := lexer.New(builder.String(), lexer.XML)
+	require.NoError(t, err)
+
+	// the value is joined once the element closes, so it does not take time quadratic in the segments
+	parser := parser2.NewWithOptions(l, parser2.Options{Whitespace: parser2.WhitespacePreserve})
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	r := doc.Elements[0].(*ast.ElementTagNode)
+	require.Len(t, r.Children, 2*segments+1)
+	require.Equal(t, strings.Repeat("t \n", segments), r.Value.Value)
+	require.Equal(t, token.Position{Offset: 3, Char: 3, Line: 1, Column: 4}, r.Value.Token.Pos)
+}
+
+func TestComments(t *testing.T) {
+	input := string(loadDataFile(t, "commentTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+	require.Equal(t, 2, len(doc.Elements))
+
+	header := doc.Elements[0].(*ast.CommentNode)
+	require.Equal(t, " Licensed under the MIT license ", header.Value)
+	require.Equal(t, token.Position{Offset: 0, Char: 0, Line: 1, Column: 1}, header.Pos())
+
+	person := doc.Elements[1].(*ast.ElementTagNode)
+	require.Nil(t, person.Value.Value)
+	require.Equal(t, 2, len(person.Elements))
+
+	expected := []string{" given name only ", "name", "<ssn>000-00-0000</ssn>", "phone"}
+	require.Equal(t, len(expected), len(person.Children))
+	for i, child := range person.Children {
+		require.Equal(t, expected[i], child.TokenLiteral())
+	}
+	require.IsType(t, &ast.CommentNode{}, person.Children[2])
+}
+
+func TestCDataSections(t *testing.T) {
+	input := string(loadDataFile(t, "cdataTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	snippet := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, 3, len(snippet.Elements))
+
+	body := snippet.Elements[1]
+	require.Equal(t, `<p class="note">Use &amp; for & </p>`, body.Value.Value)
+	require.Equal(t, 1, len(body.Children))
+	require.Equal(t, &ast.CDataNode{
+		Token: token.Token{Type: token.CDATA, Literal: `<p class="note">Use &amp; for & </p>`, Pos: token.Position{Offset: 60, Char: 60, Line: 3, Column: 11}},
+		Value: `<p class="note">Use &amp; for & </p>`,
+	}, body.Children[0])
+
+	// a "]]>" can only be written by splitting the text across two sections
+	query := snippet.Elements[2]
+	require.Equal(t, "SELECT * FROM t WHERE a < 10 AND b <> ']]>'", query.Value.Value)
+	require.Equal(t, 2, len(query.Children))
+}
+
+func TestDeclarationAndProcessingInstructions(t *testing.T) {
+	input := string(loadDataFile(t, "declarationTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+	require.Equal(t, &ast.Declaration{
+		Token:      token.Token{Type: token.PI, Literal: `xml version="1.0" encoding="UTF-8" standalone="yes"`, Pos: token.Position{Offset: 0, Char: 0, Line: 1, Column: 1}},
+		Version:    "1.0",
+		Encoding:   "UTF-8",
+		Standalone: "yes",
+	}, doc.Declaration)
+
+	require.Equal(t, 2, len(doc.Elements))
+	stylesheet := doc.Elements[0].(*ast.ProcInstNode)
+	require.Equal(t, "xml-stylesheet", stylesheet.Target)
+	require.Equal(t, `type="text/xsl" href="people.xsl"`, stylesheet.Data)
+
+	people := doc.Elements[1].(*ast.ElementTagNode)
+	require.Equal(t, 2, len(people.Children))
+	require.Equal(t, &ast.ProcInstNode{
+		Token:  token.Token{Type: token.PI, Literal: `sort by="name"`, Pos: token.Position{Offset: 122, Char: 122, Line: 4, Column: 5}},
+		Target: "sort",
+		Data:   `by="name"`,
+	}, people.Children[0])
+}
+
+func TestDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<?xml encoding="UTF-8"?><a/>`, "1:1: the xml declaration is missing its version"},
+		{`<?xml encoding="UTF-8" version="1.0"?><a/>`, "1:1: unexpected 'version' in the xml declaration"},
+		{`<?xml version="1.0" author="me"?><a/>`, "1:1: unexpected 'author' in the xml declaration"},
+		{`<?xml version="2.0"?><a/>`, "1:1: unsupported xml version '2.0'"},
+		{`<?xml version="1.0" standalone="maybe"?><a/>`, "1:1: standalone must be 'yes' or 'no', got 'maybe'"},
+		{`<?xml version=1.0?><a/>`, "1:1: malformed xml declaration 'version=1.0'"},
+		{` <?xml version="1.0"?><a/>`, "1:2: the xml declaration is only allowed at the very start of the document"},
+		{`<a><?xml version="1.0"?></a>`, "1:4: the xml declaration is only allowed at the very start of the document (in /a)"},
+		{`<a><?XML x?></a>`, "1:4: processing instruction target 'XML' is reserved (in /a)"},
+		{`<? x?><a/>`, "1:1: processing instruction has no target"},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		doc := parser.ParseDocument()
+		require.Equal(t, []string{tt.expected}, errorStrings(parser.Errors()), tt.input)
+		require.Equal(t, parser2.ErrInvalidDeclaration, parser.Errors()[0].Kind)
+		require.Nil(t, doc.Declaration)
+	}
+}
+
+func TestDoctype(t *testing.T) {
+	input := string(loadDataFile(t, "doctypeTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+	require.Equal(t, 1, len(doc.Elements))
+
+	doctype := doc.Doctype
+	require.Equal(t, "catalog", doctype.Name)
+	require.Equal(t, "", doctype.PublicId)
+	require.Equal(t, "catalog.dtd", doctype.SystemId)
+
+	require.Equal(t, 3, len(doctype.Entities))
+	require.Equal(t, &ast.EntityDecl{
+		Token: token.Token{Type: token.DOCTYPE, Literal: "vendor", Pos: token.Position{Offset: 118, Char: 118, Line: 4, Column: 5}},
+		Name:  "vendor",
+		Value: "Acme &amp; Sons",
+	}, doctype.Entity("vendor"))
+	require.Nil(t, doctype.Entity("shared"))
+	require.True(t, doctype.Entities[1].Parameter)
+	require.Equal(t, "shared.ent", doctype.Entities[1].SystemId)
+	require.Equal(t, "png", doctype.Entity("logo").Notation)
+	require.True(t, doctype.Entity("logo").IsExternal())
+
+	require.Equal(t, 2, len(doctype.Elements))
+	require.Equal(t, "(item)*", doctype.Elements[0].Content)
+	require.Equal(t, "(#PCDATA)", doctype.Elements[1].Content)
+
+	require.Equal(t, 3, len(doctype.Attributes))
+	require.Equal(t, &ast.AttributeDecl{Element: "item", Name: "id", Type: "ID", Mode: "#REQUIRED"}, withoutToken(doctype.Attributes[0]))
+	require.Equal(t, &ast.AttributeDecl{Element: "item", Name: "currency", Type: "(USD|EUR)", Default: "USD"}, withoutToken(doctype.Attributes[1]))
+	require.Equal(t, &ast.AttributeDecl{Element: "item", Name: "source", Type: "CDATA", Mode: "#FIXED", Default: "feed>v2"}, withoutToken(doctype.Attributes[2]))
+	require.Equal(t, doctype.Attributes[1:], doctype.AttributeDefaults("item"))
+}
+
+func TestAttributeDefaults(t *testing.T) {
+	input := `<!DOCTYPE a [
+<!ENTITY co "AT&amp;T">
+<!ATTLIST a id CDATA "7" kind (x|y) #IMPLIED owner CDATA #FIXED "&co;">
+<!ATTLIST a id CDATA "8" xmlns:p CDATA #FIXED "urn:p">
+<!ATTLIST b id CDATA "9">
+]>
+<a id="1"><a p:x="2"/><b/></a>`
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	attributes := func(el *ast.ElementTagNode) []string {
+		var attrs []string
+		for _, attr := range el.Attributes {
+			attrs = append(attrs, attr.Key.Value+"="+attr.Value.Value)
+		}
+		return attrs
+	}
+
+	a := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, []string{"id=1", "owner=AT&T", "xmlns:p=urn:p"}, attributes(a))
+	require.Equal(t, []string{"p:x=2", "id=7", "owner=AT&T", "xmlns:p=urn:p"}, attributes(a.Elements[0]))
+	require.Equal(t, "urn:p", a.Elements[0].Attributes[0].Key.Name.Space)
+	require.Equal(t, []string{"id=9"}, attributes(a.Elements[1]))
+
+	// defaulted attributes point at their declaration
+	require.Equal(t, 5, a.Elements[1].Attributes[0].Pos().Line)
+}
+
+func withoutToken(attr *ast.AttributeDecl) *ast.AttributeDecl {
+	copied := *attr
+	copied.Token = token.Token{}
+	return &copied
+}
+
+func TestDoctypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<!DOCTYPE>`, "1:10: expected whitespace after <!DOCTYPE"},
+		{`<!DOCTYPE a PUBLIC "id">`, "1:24: expected a quoted system identifier after the public identifier"},
+		{`<!DOCTYPE a [<!ENTITY b>]>`, "1:24: entity 'b' has neither a value nor a system identifier"},
+		{"<!DOCTYPE a [\n  <!ATTLIST a b CDATA #DEFAULT>]>", "2:23: unknown default #DEFAULT for attribute 'b' of 'a'"},
+		{`<!DOCTYPE a [<!ELEMENT a>]>`, "1:25: element declaration 'a' has no content model"},
+		{`<!DOCTYPE a [<a/>]>`, `1:14: unexpected "<a/>]" in the internal DTD subset`},
+		{`<!DOCTYPE a [ ] x>`, `1:17: unexpected "x" in the DOCTYPE`},
+		{`<a/><!DOCTYPE a>`, "1:5: the DOCTYPE must come before the root element"},
+		{`<!DOCTYPE a><!DOCTYPE a><a/>`, "1:13: the document has more than one DOCTYPE"},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		parser.ParseDocument()
+		require.Equal(t, []string{tt.expected}, errorStrings(parser.Errors()), tt.input)
+		require.Equal(t, parser2.ErrInvalidDeclaration, parser.Errors()[0].Kind)
+	}
+}
+
+func TestReferenceErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     parser2.ErrorKind
+		expected string
+	}{
+		{`<a>&nbsp;</a>`, parser2.ErrUndefinedEntity, "1:4: undefined entity &nbsp; (in /a)"},
+		{`<a>AT&T</a>`, parser2.ErrInvalidReference, "1:6: '&' must be escaped as &amp; or start a reference like &name; (in /a)"},
+		{`<a b="x&#0;"/>`, parser2.ErrInvalidReference, "1:8: &#0; is not a valid character reference (in /a)"},
+		{`<a>&#xD800;</a>`, parser2.ErrInvalidReference, "1:4: &#xD800; is not a valid character reference (in /a)"},
+		{`<a>&#12a;</a>`, parser2.ErrInvalidReference, "1:4: &#12a; is not a valid character reference (in /a)"},
+		{`<!DOCTYPE a [<!ENTITY x SYSTEM "x.txt">]><a>&x;</a>`, parser2.ErrUndefinedEntity, "1:45: entity &x; is external and is not loaded (in /a)"},
+		{`<!DOCTYPE a [<!ENTITY x "&y;"><!ENTITY y "1&x;">]><a>2&x;</a>`, parser2.ErrInvalidReference, "1:55: entity &x; refers to itself (in /a)"},
+		{`<!DOCTYPE a [<!ENTITY x "&z;">]><a>&x;</a>`, parser2.ErrUndefinedEntity, "1:36: undefined entity &z; (in /a)"},
+		{`<!DOCTYPE a [<!ENTITY e "<b>x</b>">]><a>1&e;</a>`, parser2.ErrInvalidReference, "1:42: entity &e; contains markup, which is not expanded (in /a)"},
+		{`<!DOCTYPE a [<!ENTITY e "<b/>"><!ENTITY x "&e;">]><a t="&x;"/>`, parser2.ErrInvalidReference, "1:57: entity &e; contains markup, which is not expanded (in /a)"},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		parser.ParseDocument()
+		require.Equal(t, []string{tt.expected}, errorStrings(parser.Errors()), tt.input)
+		require.Equal(t, tt.kind, parser.Errors()[0].Kind)
+	}
+}
+
+func TestEntityExpansionIsLimited(t *testing.T) {
+	var builder strings.Builder
+	builder.WriteString(`<!DOCTYPE a [<!ENTITY l0 "lol">`)
+	for i := 1; i < 10; i++ {
+		fmt.Fprintf(&builder, `<!ENTITY l%d "%s">`, i, strings.Repeat(fmt.Sprintf("&l%d;", i-1), 10))
+	}
+	builder.WriteString(`]><a>&l9;</a>`)
+
+	l, err := lexer.New(builder.String(), lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	doc := parser.ParseDocument()
+	require.Equal(t, 1, len(parser.Errors()))
+	require.Contains(t, parser.Errors()[0].Message, "expands to more than 1048576 bytes")
+	require.Equal(t, "&l9;", doc.Elements[0].(*ast.ElementTagNode).Value.Value)
+}
+
+func TestParseDecodesDeclaredEncoding(t *testing.T) {
+	input := `<?xml version="1.0" encoding="ISO-8859-1"?><a><!--caf` + "\xe9" + `--></a>`
+
+	node, err := parser2.Parse(input, parser2.XML)
+	require.NoError(t, err)
+
+	doc := node.(*ast.Document)
+	require.Equal(t, "ISO-8859-1", doc.Declaration.Encoding)
+	require.Equal(t, "caf\u00e9", doc.Elements[0].(*ast.ElementTagNode).Children[0].(*ast.CommentNode).Value)
+
+	_, err = parser2.Parse(`<?xml version="1.0" encoding="EBCDIC"?><a/>`, parser2.XML)
+	require.EqualError(t, err, `unsupported encoding "EBCDIC"`)
+}
+
+func TestNamespaces(t *testing.T) {
+	input := string(loadDataFile(t, "namespaceTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	soapNs := "http://schemas.xmlsoap.org/soap/envelope/"
+	envelope := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, ast.Name{Prefix: "soap", Local: "Envelope", Space: soapNs}, envelope.Name)
+	require.Equal(t, []ast.Namespace{{Prefix: "soap", URI: soapNs}, {URI: "urn:people"}}, envelope.Namespaces)
+	require.Equal(t, ast.Name{Prefix: "xmlns", Local: "soap", Space: ast.XmlnsNamespace}, envelope.Attributes[0].Key.Name)
+	require.Equal(t, ast.Name{Local: "xmlns", Space: ast.XmlnsNamespace}, envelope.Attributes[1].Key.Name)
+
+	body := envelope.Elements[0]
+	require.Equal(t, ast.Name{Prefix: "soap", Local: "Body", Space: soapNs}, body.Name)
+	require.Empty(t, body.Namespaces)
+
+	// unprefixed elements are in the default namespace but unprefixed attributes are in none
+	person := body.Elements[0]
+	require.Equal(t, ast.Name{Local: "person", Space: "urn:people"}, person.Name)
+	require.Equal(t, []ast.Namespace{{Prefix: "ext", URI: "urn:ext"}}, person.Namespaces)
+	require.Equal(t, ast.Name{Prefix: "ext", Local: "id", Space: "urn:ext"}, person.Attributes[1].Key.Name)
+	require.Equal(t, ast.Name{Local: "role"}, person.Attributes[2].Key.Name)
+	require.Equal(t, ast.Name{Prefix: "xml", Local: "lang", Space: ast.XmlNamespace}, person.Attributes[3].Key.Name)
+
+	require.Equal(t, ast.Name{Local: "name", Space: "urn:people"}, person.Elements[0].Name)
+
+	// xmlns="" undeclares the default namespace for the element and its content only
+	nickname := person.Elements[1]
+	require.Equal(t, ast.Name{Prefix: "ext", Local: "nickname", Space: "urn:ext"}, nickname.Name)
+	require.Equal(t, []ast.Namespace{{}}, nickname.Namespaces)
+	require.Equal(t, ast.Name{Local: "note", Space: "urn:people"}, person.Elements[2].Name)
+}
+
+func TestNamespaceErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     parser2.ErrorKind
+		expected string
+	}{
+		{`<a:b/>`, parser2.ErrUnboundPrefix, "1:2: namespace prefix 'a' of 'a:b' is not bound (in /a:b)"},
+		{`<a x:y="1"/>`, parser2.ErrUnboundPrefix, "1:4: namespace prefix 'x' of 'x:y' is not bound (in /a)"},
+		{`<a xmlns:x="urn:x"/><x:b/>`, parser2.ErrUnboundPrefix, "1:22: namespace prefix 'x' of 'x:b' is not bound (in /x:b)"},
+		{`<a:b:c xmlns:a="urn:a"/>`, parser2.ErrInvalidNamespace, "1:2: 'a:b:c' is not a valid qualified name (in /a:b:c)"},
+		{`<a xmlns:x=""/>`, parser2.ErrInvalidNamespace, "1:4: namespace prefix 'x' can not be undeclared (in /a)"},
+		{`<a xmlns:xmlns="urn:x"/>`, parser2.ErrInvalidNamespace, "1:4: the xmlns prefix can not be declared (in /a)"},
+		{`<a xmlns:xml="urn:x"/>`, parser2.ErrInvalidNamespace, "1:4: the xml prefix can only be bound to http://www.w3.org/XML/1998/namespace (in /a)"},
+		{`<a xmlns:x="urn:x" xmlns:y="urn:x" x:b="1" y:b="2"/>`, parser2.ErrDuplicateAttribute, "1:44: attribute 'y:b' is given more than once (in /a)"},
+		{`<a b="1" b="2"/>`, parser2.ErrDuplicateAttribute, "1:10: attribute 'b' is given more than once (in /a)"},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		parser.ParseDocument()
+
+		errs := parser.Errors()
+		require.Len(t, errs, 1, tt.input)
+		require.Equal(t, tt.kind, errs[0].Kind, tt.input)
+		require.Equal(t, tt.expected, errs[0].Error(), tt.input)
+	}
+
+	// the same local name in different namespaces is not a duplicate
+	l, err := lexer.New(`<a xmlns:x="urn:x" xmlns:y="urn:y" b="1" x:b="2" y:b="3"/>`, lexer.XML)
+	require.NoError(t, err)
+	parser := parser2.New(l)
+	parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+}
+
+func TestWhitespaceModes(t *testing.T) {
+	input := string(loadDataFile(t, "whitespaceTest.xml"))
+
+	tests := []struct {
+		mode     parser2.WhitespaceMode
+		name     string
+		children int // text and element content of the root
+		line     string
+	}{
+		{parser2.WhitespaceTrim, "Justin   Dodson", 3, "violets   are blue"},
+		{parser2.WhitespaceCollapse, "Justin Dodson", 3, "violets are blue"},
+		{parser2.WhitespacePreserve, "  Justin   Dodson ", 7, "  violets   are blue  "},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.NewWithOptions(l, parser2.Options{Whitespace: tt.mode})
+		doc := parser.ParseDocument()
+		require.Empty(t, parser.Errors())
+
+		customer := doc.Elements[0].(*ast.ElementTagNode)
+		require.Len(t, customer.Children, tt.children, "mode %d", tt.mode)
+		require.Equal(t, tt.name, customer.Elements[0].Value.Value, "mode %d", tt.mode)
+
+		// xml:space="preserve" keeps the whitespace of the element and its content in every mode,
+		// until xml:space="default" returns to the mode of the parser
+		require.Equal(t, "12 Main St\n  Apt  4", customer.Elements[1].Value.Value, "mode %d", tt.mode)
+		poem := customer.Elements[2]
+		require.Equal(t, "\n\t\t\n\t\t\n\t", poem.Value.Value, "mode %d", tt.mode)
+		require.Equal(t, " roses  are red ", poem.Elements[0].Value.Value, "mode %d", tt.mode)
+		require.Equal(t, tt.line, poem.Elements[1].Value.Value, "mode %d", tt.mode)
+	}
+}
+
+func TestWhitespaceAroundInlineElements(t *testing.T) {
+	input := "<p>\n  <!-- c -->Hello   <b> world </b>\tagain &#32;<i/>\n</p>"
+
+	tests := []struct {
+		mode     parser2.WhitespaceMode
+		segments []string
+	}{
+		{parser2.WhitespaceTrim, []string{"Hello   ", "world", "\tagain  "}},
+		{parser2.WhitespaceCollapse, []string{"Hello ", "world", " again "}},
+		{parser2.WhitespacePreserve, []string{"\n  ", "Hello   ", " world ", "\tagain  ", "\n"}},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.NewWithOptions(l, parser2.Options{Whitespace: tt.mode})
+		doc := parser.ParseDocument()
+		require.Empty(t, parser.Errors())
+
+		var segments []string
+		var walk func(el *ast.ElementTagNode)
+		walk = func(el *ast.ElementTagNode) {
+			for _, child := range el.Children {
+				switch n := child.(type) {
+				case *ast.ElementValueNode:
+					segments = append(segments, n.Value.(string))
+				case *ast.ElementTagNode:
+					walk(n)
+				}
+			}
+		}
+		walk(doc.Elements[0].(*ast.ElementTagNode))
+		require.Equal(t, tt.segments, segments, "mode %d", tt.mode)
+	}
+}
+
+func TestTrimmedTextPosition(t *testing.T) {
+	l, err := lexer.New("<a>\n  x &bad; </a>", lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.NewWithOptions(l, parser2.Options{Whitespace: parser2.WhitespaceCollapse})
+	doc := parser.ParseDocument()
+	require.Equal(t, []string{"2:5: undefined entity &bad; (in /a)"}, errorStrings(parser.Errors()))
+
+	a := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, token.Position{Offset: 6, Char: 6, Line: 2, Column: 3}, a.Value.Token.Pos)
+	require.Equal(t, "x &bad;", a.Value.Value)
+}
+
+func TestNodePositions(t *testing.T) {
+	input := string(loadDataFile(t, "nestedElementsTest.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	doc := parser.ParseDocument()
+	require.Empty(t, parser.Errors())
+
+	employee := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, token.Position{Offset: 1, Char: 1, Line: 1, Column: 2}, employee.Pos())
+	require.Equal(t, token.Position{Offset: 10, Char: 10, Line: 1, Column: 11}, employee.Attributes[0].Pos())
+	require.Equal(t, token.Position{Offset: 16, Char: 16, Line: 1, Column: 17}, employee.Attributes[0].Value.Pos())
+	require.Equal(t, token.Position{Offset: 123, Char: 123, Line: 5, Column: 3}, employee.EndToken.Pos)
+
+	phone := employee.Elements[2]
+	require.Equal(t, 4, phone.Pos().Line)
+	require.Equal(t, 6, phone.Pos().Column)
+	require.Equal(t, token.Position{Offset: 104, Char: 104, Line: 4, Column: 26}, phone.Value.Pos())
+	require.Equal(t, doc.Pos(), employee.Pos())
+}
+
+func TestStructuredParseErrors(t *testing.T) {
+	l, err := lexer.New("<people>\n  <person><name>Justin</nme></person>\n</people>", lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	parser.ParseDocument()
+
+	errs := parser.Errors()
+	require.NotEmpty(t, errs)
+
+	mismatch := errs[0]
+	require.Equal(t, parser2.ErrMismatchedTag, mismatch.Kind)
+	require.Equal(t, token.Position{Offset: 33, Char: 33, Line: 2, Column: 25}, mismatch.Pos)
+	require.Equal(t, []token.TokenType{token.TAG}, mismatch.Expected)
+	require.Equal(t, "nme", mismatch.Actual.Literal)
+	require.Equal(t, []string{"people", "person", "name"}, mismatch.Path)
+	require.Equal(t, "2:25: Mismatching closing tag 'nme' for element 'name' (in /people/person/name)", mismatch.Error())
+}
+
+func TestParseErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     parser2.ErrorKind
+		expected []token.TokenType
+		actual   token.TokenType
+	}{
+		{`<a>1`, parser2.ErrUnexpectedEOF, []token.TokenType{token.VALUE, token.OPEN_ANGLE}, token.EOF},
+		{`<a b="1'>1</a>`, parser2.ErrMismatchedQuotes, []token.TokenType{token.QUOTE}, token.SINGLE_QUOTE},
+		{`<a b=c>1</a>`, parser2.ErrUnexpectedToken, []token.TokenType{token.QUOTE, token.SINGLE_QUOTE}, token.KEY},
+		{`<a>1<!b></a>`, parser2.ErrIllegalToken, []token.TokenType{token.TAG, token.XML_TERMINATOR}, token.ILLEGAL},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		parser.ParseDocument()
+
+		errs := parser.Errors()
+		require.NotEmpty(t, errs, tt.input)
+		require.Equal(t, tt.kind, errs[0].Kind, tt.input)
+		require.Equal(t, tt.expected, errs[0].Expected, tt.input)
+		require.Equal(t, tt.actual, errs[0].Actual.Type, tt.input)
+		require.Equal(t, []string{"a"}, errs[0].Path, tt.input)
+	}
+}
+
+func TestParseErrorsWorkWithErrorsAs(t *testing.T) {
+	_, err := parser2.Parse(`<a><b>1</c></a>`, parser2.XML)
+	require.Error(t, err)
+
+	var list parser2.ErrorList
+	require.True(t, errors.As(err, &list))
+	require.NotEmpty(t, list)
+
+	var parseErr *parser2.ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, parser2.ErrMismatchedTag, parseErr.Kind)
+	require.Equal(t, "/a/b", parseErr.PathString())
+
+	l, err := lexer.New(`<a>1</a>`, lexer.XML)
+	require.NoError(t, err)
+	parser := parser2.New(l)
+	parser.ParseDocument()
+	require.NoError(t, parser.Err())
+}
+
+func TestErrorRecoveryKeepsTheRestOfTheTree(t *testing.T) {
+	input := string(loadDataFile(t, "fullTestFile.xml"))
+	l, err := lexer.New(input, lexer.XML)
+	require.NoError(t, err)
+
+	parser := parser2.New(l)
+	doc := parser.ParseDocument()
+
+	// the broken <ssn>999-99-99996/ssn> reads "/ssn>" as text and is implicitly closed by </person>
+	messages := errorStrings(parser.Errors())
+	require.Equal(t, []string{"19:4: Mismatching closing tag 'person' for element 'ssn' (in /people/person/ssn)"}, messages)
+
+	require.Equal(t, 1, len(doc.Elements))
+	people := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, "people", people.EndToken.Literal)
+	require.Equal(t, 6, len(people.Elements))
+
+	for _, person := range people.Elements {
+		require.Equal(t, "person", person.EndToken.Literal)
+		require.Equal(t, 4, len(person.Elements))
+	}
+
+	jimmie := people.Elements[2]
+	require.Equal(t, "999-99-99996/ssn>", jimmie.Elements[3].Value.Value)
+	require.Equal(t, token.Token{}, jimmie.Elements[3].EndToken)
+	require.Equal(t, "Wyatt", people.Elements[3].Elements[0].Value.Value)
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string
+		expected string
+	}{
+		{
+			`<a><b>1</c><d>2</d></b></a>`,
+			[]string{"1:10: Mismatching closing tag 'c' for element 'b' (in /a/b)"},
+			`<a><b>1<d>2</d></b></a>`,
+		},
+		{
+			`<a><b>1<c>2</a><d>3</d>`,
+			[]string{
+				"1:14: Mismatching closing tag 'a' for element 'c' (in /a/b/c)",
+			},
+			`<a><b>1<c>2</c></b></a><d>3</d>`,
+		},
+		{
+			`<a x=1 y="2"><b>1</b></a>`,
+			[]string{"1:6: XML element attribute values must be wrapped in quotes. (in /a)"},
+			`<a><b>1</b></a>`,
+		},
+		{
+			`</x><a>1</a>`,
+			[]string{"1:3: closing tag 'x' has no matching element"},
+			`<a>1</a>`,
+		},
+		{
+			`<a><b/ x><c>2</c></a>`,
+			[]string{"1:8: missing closing angle at element tag termination (in /a/b)"},
+			`<a><b/><c>2</c></a>`,
+		},
+		{
+			`<a><b>1</b>`,
+			[]string{"1:12: element 'a' is not closed (in /a)"},
+			`<a><b>1</b></a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		l, err := lexer.New(tt.input, lexer.XML)
+		require.NoError(t, err)
+
+		parser := parser2.New(l)
+		doc := parser.ParseDocument()
+		require.Equal(t, tt.errors, errorStrings(parser.Errors()), tt.input)
+		require.Equal(t, tt.expected, string(converter.EncodeXml(doc, "")), tt.input)
+	}
+}
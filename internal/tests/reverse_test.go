@@ -0,0 +1,386 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal/ast"
+	"github.com/jdodson3106/goXml2Json/internal/converter"
+	"github.com/jdodson3106/goXml2Json/internal/lexer"
+	"github.com/jdodson3106/goXml2Json/internal/parser"
+	"github.com/jdodson3106/goXml2Json/internal/token"
+	"github.com/stretchr/testify/require"
+)
+
+func reverseJson(t *testing.T, opts converter.Options, input string) string {
+	node, errs := parseJson(t, input)
+	require.Empty(t, errs)
+
+	out, err := converter.New(opts).ToXml(node)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestReverseMappingRules(t *testing.T) {
+	input := `{"person": {"@role": "father", "@age": 34, "name": [{"@category": "given-name", "#text": "Justin"}, "Dodson"], "spouse": null, "note": "a < b & c"}}`
+
+	require.Equal(t,
+		`<person role="father" age="34"><name category="given-name">Justin</name><name>Dodson</name><spouse/><note>a &lt; b &amp; c</note></person>`,
+		reverseJson(t, converter.Options{}, input),
+	)
+}
+
+func TestReverseKeepsNumberText(t *testing.T) {
+	input := `{"p": {"@rate": 0.000001, "price": 1234567.5, "big": 12345678901234567890, "exp": 1E+2, "neg": -0}}`
+
+	require.Equal(t,
+		`<p rate="0.000001"><price>1234567.5</price><big>12345678901234567890</big><exp>1E+2</exp><neg>-0</neg></p>`,
+		reverseJson(t, converter.Options{}, input),
+	)
+}
+
+func TestReverseRootNaming(t *testing.T) {
+	tests := []struct {
+		opts     converter.Options
+		input    string
+		expected string
+	}{
+		{converter.Options{}, `{"a": "1", "b": "2"}`, `<root><a>1</a><b>2</b></root>`},
+		{converter.Options{RootName: "doc"}, `{"a": ["1", "2"]}`, `<doc><a>1</a><a>2</a></doc>`},
+		{converter.Options{RootName: "doc"}, `{"a": "1"}`, `<a>1</a>`},
+		{converter.Options{RootName: "list"}, `[1, [true, false]]`, `<list><item>1</item><item><item>true</item><item>false</item></item></list>`},
+		{converter.Options{RootName: "value"}, `"text"`, `<value>text</value>`},
+		{converter.Options{}, `{"@id": "1"}`, `<root id="1"/>`},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, reverseJson(t, tt.opts, tt.input), tt.input)
+	}
+}
+
+func TestReverseAttributePrefixAndTextKey(t *testing.T) {
+	tests := []struct {
+		opts     converter.Options
+		input    string
+		expected string
+	}{
+		{converter.Options{AttributePrefix: prefix("-"), TextKey: "_"}, `{"a": {"-id": 1, "_": "x", "b": {"-role": "r"}}}`, `<a id="1">x<b role="r"/></a>`},
+		{converter.Options{AttributePrefix: prefix("_"), TextKey: "_"}, `{"a": {"_id": 1, "_": "x"}}`, `<a id="1">x</a>`},
+		{converter.Options{AttributePrefix: prefix(""), TextKey: "value"}, `{"a": {"id": 1, "value": "x"}}`, `<a><id>1</id>x</a>`},
+		// the text key is matched before the attribute prefix
+		{converter.Options{AttributePrefix: prefix("_"), TextKey: "_text"}, `{"a": {"_id": 1, "_text": "x"}}`, `<a id="1">x</a>`},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, reverseJson(t, tt.opts, tt.input), tt.input)
+	}
+
+	node, errs := parseJson(t, `{"item": {"@id": "1", "value": ["x", "3"]}}`)
+	require.Empty(t, errs)
+	_, err := converter.New(converter.Options{TextKey: "value"}).ToXml(node)
+	require.EqualError(t, err, "value of element item must be a string, number, boolean or null")
+}
+
+func TestRoundTripAttributePrefixAndTextKey(t *testing.T) {
+	for _, opts := range []converter.Options{{AttributePrefix: prefix("-"), TextKey: "_"}, {AttributePrefix: prefix("_"), TextKey: "value"}} {
+		c := converter.New(opts)
+		doc := parseTestFile(t, "repeatedRecordsTest.xml")
+
+		node, err := c.Convert(doc)
+		require.NoError(t, err)
+
+		reversed, err := c.Reverse(node)
+		require.NoError(t, err)
+		require.Equal(t, string(converter.EncodeXml(doc, "")), string(converter.EncodeXml(reversed, "")))
+	}
+}
+
+func TestReverseMixedSegments(t *testing.T) {
+	input := `{"p": {"@class": "intro", "#content": ["Hello", {"b": "world"}, "again"]}}`
+
+	out := reverseJson(t, converter.Options{Indent: "  "}, input)
+	require.Equal(t, `<p class="intro">Hello<b>world</b>again</p>`, out)
+}
+
+func TestReverseComments(t *testing.T) {
+	input := `{"#comment": " header ", "a": {"#comment": ["x", "y"], "b": "1"}}`
+
+	require.Equal(t, `<!-- header --><a><!--x--><!--y--><b>1</b></a>`, reverseJson(t, converter.Options{}, input))
+	require.Equal(t, "<!-- header -->\n<a>\n  <!--x-->\n  <!--y-->\n  <b>1</b>\n</a>", reverseJson(t, converter.Options{Indent: "  "}, input))
+}
+
+func TestReverseCData(t *testing.T) {
+	input := `{"js": {"@type": "text/javascript", "#cdata": ["if (a < b) {}", "x]]>y"]}}`
+
+	require.Equal(t,
+		`<js type="text/javascript"><![CDATA[if (a < b) {}]]><![CDATA[x]]]]><![CDATA[>y]]></js>`,
+		reverseJson(t, converter.Options{Indent: "  "}, input),
+	)
+}
+
+func TestReverseProcessingInstructions(t *testing.T) {
+	input := `{"?xml-stylesheet": "href=\"a.xsl\"", "a": {"?sort": ["", "by=\"name\""], "b": "1"}}`
+
+	require.Equal(t,
+		`<?xml-stylesheet href="a.xsl"?><a><?sort?><?sort by="name"?><b>1</b></a>`,
+		reverseJson(t, converter.Options{}, input),
+	)
+}
+
+func TestEncodeXmlDeclaration(t *testing.T) {
+	doc := parseTestFile(t, "declarationTest.xml")
+	doc.Declaration.Encoding = "ISO-8859-1"
+
+	require.Equal(t,
+		"<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n<?xml-stylesheet type=\"text/xsl\" href=\"people.xsl\"?>\n<people>\n  <?sort by=\"name\"?>\n  <person>Justin</person>\n</people>",
+		string(converter.EncodeXml(doc, "  ")),
+	)
+}
+
+func TestEncodeXmlDoctype(t *testing.T) {
+	l, err := lexer.New(`<!DOCTYPE a [ <!ENTITY b "c"> ]><a/>`, lexer.XML)
+	require.NoError(t, err)
+	doc := parser.New(l).ParseDocument()
+
+	require.Equal(t, `<!DOCTYPE a [ <!ENTITY b "c"> ]><a/>`, string(converter.EncodeXml(doc, "")))
+}
+
+func TestReverseIndentedOutput(t *testing.T) {
+	input := `{"a": {"b": {"c": "1"}, "d": null}}`
+
+	out := reverseJson(t, converter.Options{Indent: "  "}, input)
+	require.Equal(t, "<a>\n  <b>\n    <c>1</c>\n  </b>\n  <d/>\n</a>", out)
+}
+
+func TestReverseBindsPrefixes(t *testing.T) {
+	input := `{"a": {"@xmlns:x": "urn:x", "x:b": {"@x:c": 1, "@xml:lang": "en"}}}`
+
+	require.Equal(t,
+		`<a xmlns:x="urn:x"><x:b x:c="1" xml:lang="en"/></a>`,
+		reverseJson(t, converter.Options{}, input),
+	)
+	require.Equal(t,
+		`<x:a xmlns:x="urn:x"><x:b/></x:a>`,
+		reverseJson(t, converter.Options{Convention: converter.ConventionJsonML}, `["x:a", {"xmlns:x": "urn:x"}, ["x:b"]]`),
+	)
+}
+
+func TestReverseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"1a": "x"}`, `key "1a" is not a valid xml element name`},
+		{`{"name:": "x"}`, `key "name:" is not a valid xml element name`},
+		{`{"a:b:c": "x"}`, `key "a:b:c" is not a valid xml element name`},
+		{`{"a": {"@:b": "x"}}`, `key "@:b" is not a valid xml attribute name`},
+		{`{"a": {"x:b": "t"}}`, `prefix "x" of element x:b is not bound by an xmlns:x attribute`},
+		{`{"a": {"@y:c": 1}}`, `prefix "y" of attribute y:c of element a is not bound by an xmlns:y attribute`},
+		{`{"a": {"b": {"@xmlns:x": "urn:x"}, "x:c": null}}`, `prefix "x" of element x:c is not bound by an xmlns:x attribute`},
+		{`{"a": {"@b c": "x"}}`, `key "@b c" is not a valid xml attribute name`},
+		{`{"a": {"@b": {}}}`, `attribute "@b" of element a must be a string, number, boolean or null`},
+		{`{"a": {"#content": "x"}}`, `#content of element a must be an array`},
+		{`{"a": {"#comment": 1}}`, `#comment must be a string or an array of strings in element a`},
+		{`{"a": {"#cdata": {}}}`, `#cdata must be a string or an array of strings in element a`},
+		{`{"?xml": "version=\"1.0\"", "a": null}`, `key "?xml" is not a valid processing instruction target`},
+		{`{"a": {"?b": "?>"}}`, `?b "?>" can not be written as a processing instruction in element a`},
+		{`{"#comment": "a -- b", "a": null}`, `#comment "a -- b" can not be written as an xml comment`},
+	}
+
+	for _, tt := range tests {
+		node, errs := parseJson(t, tt.input)
+		require.Empty(t, errs)
+
+		_, err := converter.New(converter.Options{}).ToXml(node)
+		require.EqualError(t, err, tt.expected)
+	}
+}
+
+func TestRoundTripTestFiles(t *testing.T) {
+	tests := []struct {
+		convention string
+		opts       converter.Options
+		whitespace parser.WhitespaceMode
+		files      []string
+
+		// asWritten compares the xml written back with the source text instead of with the parsed document
+		asWritten bool
+	}{
+		{
+			convention: "default",
+			opts:       converter.Options{MixedContent: converter.MixedSegments, MarkCData: true},
+			files:      []string{"nestedElementsTest.xml", "repeatedRecordsTest.xml", "mixedContentTest.xml", "proseTest.xml", "cdataTest.xml", "unicodeTest.xml", "namespaceTest.xml"},
+		},
+		{
+			convention: "badgerfish",
+			opts:       converter.Options{Convention: converter.ConventionBadgerFish, MarkCData: true},
+			files:      []string{"nestedElementsTest.xml", "repeatedRecordsTest.xml", "cdataTest.xml", "unicodeTest.xml", "namespaceTest.xml"},
+		},
+		{
+			// the xml comes back exactly as written, indentation and spaces around inline elements included
+			convention: "jsonml",
+			opts:       converter.Options{Convention: converter.ConventionJsonML},
+			whitespace: parser.WhitespacePreserve,
+			files:      []string{"nestedElementsTest.xml", "repeatedRecordsTest.xml", "mixedContentTest.xml", "proseTest.xml", "unicodeTest.xml", "namespaceTest.xml", "whitespaceTest.xml"},
+			asWritten:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, f := range tt.files {
+			t.Run(tt.convention+"/"+f, func(t *testing.T) {
+				source := string(loadDataFile(t, f))
+				l, err := lexer.New(source, lexer.XML)
+				require.NoError(t, err)
+				p := parser.NewWithOptions(l, parser.Options{Whitespace: tt.whitespace})
+				doc := p.ParseDocument()
+				require.Empty(t, p.Errors())
+
+				c := converter.New(tt.opts)
+				node, err := c.Convert(doc)
+				require.NoError(t, err)
+
+				if tt.asWritten {
+					out, err := c.ToXml(node)
+					require.NoError(t, err)
+					require.Equal(t, strings.TrimSuffix(source, "\n"), string(out))
+					return
+				}
+
+				reversed, err := c.Reverse(node)
+				require.NoError(t, err)
+				require.Equal(t, string(converter.EncodeXml(doc, "")), string(converter.EncodeXml(reversed, "")))
+			})
+		}
+	}
+}
+
+func TestReverseBadgerFish(t *testing.T) {
+	opts := converter.Options{Convention: converter.ConventionBadgerFish}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": {"@id": 1, "$": "x", "b": [{"$": "y"}, {}, null]}}`, `<a id="1">x<b>y</b><b/><b/></a>`},
+		// namespaces are declared where they first appear and inherited when left out
+		{`{"a": {"@xmlns": {"$": "urn:a"}, "b": {"@xmlns": {"$": "urn:a", "c": "urn:c"}, "c:d": "z"}}}`, `<a xmlns="urn:a"><b xmlns:c="urn:c"><c:d>z</c:d></b></a>`},
+		{`{"a": {"@xmlns": {"$": "urn:a"}, "b": {"@xmlns": {}}}}`, `<a xmlns="urn:a"><b xmlns=""/></a>`},
+		{`{"a": "x", "b": "y"}`, `<root><a>x</a><b>y</b></root>`},
+		{`{"#comment": " c ", "a": {"#cdata": "<x>"}}`, `<!-- c --><a><![CDATA[<x>]]></a>`},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, reverseJson(t, opts, tt.input), tt.input)
+	}
+}
+
+func TestReverseBadgerFishErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`["a"]`, `a BadgerFish document must be an object, got *ast.JsonArrayNode`},
+		{`{"a": {"$": {}}}`, `$ of element a must be a string, number, boolean or null`},
+		{`{"a": {"@xmlns": "urn:a"}}`, `@xmlns of element a must be an object`},
+		{`{"a": {"@xmlns": {"$": ""}}}`, `namespace "$" of element a must be a non empty string`},
+		{`{"a": {"@xmlns": {"b:c": "urn:c"}}}`, `key "b:c" is not a valid namespace prefix`},
+		{`{"a": {"b": [[1]]}}`, `array in array for element b can not be written as BadgerFish xml`},
+	}
+
+	for _, tt := range tests {
+		node, errs := parseJson(t, tt.input)
+		require.Empty(t, errs)
+
+		_, err := converter.New(converter.Options{Convention: converter.ConventionBadgerFish}).ToXml(node)
+		require.EqualError(t, err, tt.expected)
+	}
+}
+
+func TestReverseParker(t *testing.T) {
+	c := converter.New(converter.Options{Convention: converter.ConventionParker})
+	doc := parseTestFile(t, "repeatedRecordsTest.xml")
+
+	node, err := c.Convert(doc)
+	require.NoError(t, err)
+
+	reversed, err := c.Reverse(node)
+	require.NoError(t, err)
+	require.Equal(t,
+		`<people><person><name>Justin</name><name>Dodson</name><dob>09-27-1989</dob><ssn>999-99-9994</ssn></person>`+
+			`<person><name>Diana</name><name>Dodson</name><dob>03-04-1988</dob><ssn>999-99-9995</ssn></person>`+
+			`<person><name>Wyatt</name><name>Dodson</name><dob>12-08-2009</dob><ssn>999-99-9997</ssn></person></people>`,
+		string(converter.EncodeXml(reversed, "")),
+	)
+}
+
+func TestReverseGDataIsRejected(t *testing.T) {
+	node, errs := parseJson(t, `{"a": {"id": "1", "$t": "x"}}`)
+	require.Empty(t, errs)
+
+	_, err := converter.New(converter.Options{Convention: converter.ConventionGData}).Reverse(node)
+	require.EqualError(t, err, "GData JSON can not be converted back to xml")
+}
+
+func TestReverseJsonML(t *testing.T) {
+	opts := converter.Options{Convention: converter.ConventionJsonML}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`["p", {"class": "intro", "n": 1}, "Hello ", ["b", "bold"], " and ", ["i"], null, 3]`, `<p class="intro" n="1">Hello <b>bold</b> and <i/>3</p>`},
+		{`["a", ["b", {}, "x & y"]]`, `<a><b>x &amp; y</b></a>`},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, reverseJson(t, opts, tt.input), tt.input)
+	}
+}
+
+func TestReverseJsonMLErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": "x"}`, `a JsonML element must be an array, got *ast.JsonObjectNode`},
+		{`[]`, `a JsonML element must start with its tag name`},
+		{`[1]`, `a JsonML element must start with its tag name, got *ast.JsonNumberNode`},
+		{`["1a"]`, `"1a" is not a valid xml element name`},
+		{`["a", {"b c": "x"}]`, `key "b c" is not a valid xml attribute name`},
+		{`["a", {"b": ["x"]}]`, `attribute "b" of element a must be a string, number, boolean or null`},
+		{`["a", "x", {"b": "y"}]`, `the attributes of JsonML element a must directly follow its tag name`},
+	}
+
+	for _, tt := range tests {
+		node, errs := parseJson(t, tt.input)
+		require.Empty(t, errs)
+
+		_, err := converter.New(converter.Options{Convention: converter.ConventionJsonML}).ToXml(node)
+		require.EqualError(t, err, tt.expected)
+	}
+}
+
+func TestReverseBuildsParserShapedNodes(t *testing.T) {
+	node, errs := parseJson(t, `{"name": "Justin", "dob": null}`)
+	require.Empty(t, errs)
+
+	doc, err := converter.New(converter.Options{}).Reverse(node)
+	require.NoError(t, err)
+
+	root := doc.Elements[0].(*ast.ElementTagNode)
+	require.Equal(t, token.Token{Type: token.TAG, Literal: "root"}, root.EndToken)
+
+	name := root.Elements[0]
+	require.Equal(t, token.Token{Type: token.TAG, Literal: "name"}, name.Token)
+	require.Equal(t, token.Token{Type: token.TAG, Literal: "name"}, name.EndToken)
+	require.Equal(t, "Justin", name.Value.Value)
+	require.Equal(t, []ast.ElementNode{&name.Value}, name.Children)
+
+	// generated nodes have no position in any input
+	require.False(t, name.Pos().IsValid())
+
+	dob := root.Elements[1]
+	require.Equal(t, token.Token{Type: token.CLOSE_ANGLE, Literal: ">"}, dob.EndToken)
+	require.Empty(t, dob.Children)
+}
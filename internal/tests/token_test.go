@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/jdodson3106/goXml2Json/internal/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupValue(t *testing.T) {
+	tests := []struct {
+		literal  string
+		expected token.TokenType
+	}{
+		{"35", token.INT},
+		{"-12", token.INT},
+		{"0", token.INT},
+		{"8675309", token.INT},
+		{"3.14", token.FLOAT},
+		{"-0.5", token.FLOAT},
+		{"true", token.BOOL},
+		{"false", token.BOOL},
+		{"007", token.VALUE},
+		{"+1", token.VALUE},
+		{"-0", token.VALUE},
+		{"1.50", token.VALUE},
+		{"1e5", token.VALUE},
+		{"99999999999999999999", token.VALUE},
+		{"999-99-9994", token.VALUE},
+		{"09-27-1989", token.VALUE},
+		{"NaN", token.VALUE},
+		{"Inf", token.VALUE},
+		{"True", token.VALUE},
+		{"", token.VALUE},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, token.LookupValue(tt.literal), tt.literal)
+	}
+}
+
+func TestLookupKeyword(t *testing.T) {
+	require.Equal(t, token.TokenType(token.BOOL), token.LookupKeyword("true"))
+	require.Equal(t, token.TokenType(token.NULL), token.LookupKeyword("null"))
+	require.Equal(t, token.TokenType(token.ILLEGAL), token.LookupKeyword("nil"))
+}
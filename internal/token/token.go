@@ -1,19 +1,32 @@
 package token
 
+import (
+	"fmt"
+	"strconv"
+)
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
 	// Literals
-	INT   = "INT"   // 1343456
-	FLOAT = "FLOAT" // 3.1415926535
-	BOOL  = "BOOL"
+	INT    = "INT"   // 1343456
+	FLOAT  = "FLOAT" // 3.1415926535
+	BOOL   = "BOOL"
+	STRING = "STRING" // json strings, the literal holds the unescaped value
+	NULL   = "NULL"
 
 	// Identifiers
 	TAG   = "TAG" // xml has tag names to parse (these will convert into json object names)
 	KEY   = "KEY" // xml and json both have key/value pairs
 	VALUE = "VALUE"
 
+	// xml markup
+	COMMENT = "COMMENT" // <!-- comment -->, the literal holds the text between the delimiters
+	CDATA   = "CDATA"   // <![CDATA[ text ]]>, the literal holds the verbatim text between the delimiters
+	PI      = "PI"      // <?target data?>, the literal holds the text between the delimiters
+	DOCTYPE = "DOCTYPE" // <!DOCTYPE name [ subset ]>, the literal holds the text after the keyword
+
 	// xml delimiter tokens
 	XML_TERMINATOR = "/"
 	OPEN_ANGLE     = "<"
@@ -38,4 +51,71 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // where the first char of the token starts in the input
+}
+
+// Position locates a char in the lexer input, both in bytes and in unicode chars
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Char   int // char offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in chars, starting at 1
+}
+
+// IsValid reports whether the position was set by a lexer. Tokens created
+// by the converters have no position in any input
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+var keywords = map[string]TokenType{
+	"true":  BOOL,
+	"false": BOOL,
+	"null":  NULL,
+}
+
+// LookupKeyword returns the TokenType of a json keyword or ILLEGAL if word is not a keyword
+func LookupKeyword(word string) TokenType {
+	if t, ok := keywords[word]; ok {
+		return t
+	}
+	return ILLEGAL
+}
+
+/*
+LookupValue - classifies the text of an xml value as an INT, FLOAT or BOOL literal,
+returning VALUE for anything else. A literal is only classified when formatting the parsed value
+produces exactly the same text, so identifiers like "007", "+1", "1.50" or "999-99-9994" stay
+VALUE and nothing is lost converting them
+*/
+func LookupValue(literal string) TokenType {
+	switch literal {
+	case "true", "false":
+		return BOOL
+	case "":
+		return VALUE
+	}
+
+	// rules out NaN, Inf and the other special forms strconv accepts
+	if c := literal[0]; c != '-' && (c < '0' || c > '9') {
+		return VALUE
+	}
+
+	if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		if strconv.FormatInt(i, 10) == literal {
+			return INT
+		}
+		return VALUE
+	}
+
+	if f, err := strconv.ParseFloat(literal, 64); err == nil {
+		if strconv.FormatFloat(f, 'f', -1, 64) == literal {
+			return FLOAT
+		}
+	}
+	return VALUE
 }